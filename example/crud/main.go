@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -15,8 +14,12 @@ type Result struct {
 
 type User struct {
 	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Age  int    `json:"age"`
+	Name string `json:"name" validate:"required,min=2,max=50"`
+	Age  int    `json:"age" validate:"max=150"`
+}
+
+type UserPath struct {
+	Id int `path:"id" validate:"min=1"`
 }
 
 // Define dummy users
@@ -34,7 +37,7 @@ func main() {
 	server.SetHeaders(headers)
 
 	server.AddHandler("GET /users", func(req *gohst.Request, res *gohst.Response) {
-		s := req.Query["search"]
+		s := req.Query.Get("search")
 		filteredUsers := make([]User, 0)
 		if s != "" {
 			for _, u := range users {
@@ -47,62 +50,56 @@ func main() {
 			copy(filteredUsers, users)
 		}
 
-		json, _ := json.Marshal(filteredUsers)
-		res.Body = string(json)
+		res.JSON(200, filteredUsers)
 	})
 
-	server.AddHandler("GET /users/:id", func(req *gohst.Request, res *gohst.Response) {
-		id := req.Params["id"]
-		userId, err := strconv.Atoi(id)
-		if err != nil {
-			res.StatusCode = 400
-			json, _ := json.Marshal(Result{Message: "Invalid user id"})
-			res.Body = string(json)
-			return
-		}
-
-		user := User{}
+	// Typed handlers bind path params, query and headers into the input and encode the result
+	server.AddHandler("GET /users/:id", gohst.JSONHandler(func(req *gohst.Request, in UserPath) (User, error) {
 		for _, u := range users {
-			if u.Id == userId {
-				user = u
-				break
+			if u.Id == in.Id {
+				return u, nil
 			}
 		}
-
-		if user.Id == 0 {
-			res.StatusCode = 404
-			json, _ := json.Marshal(Result{Message: "User not found"})
-			res.Body = string(json)
-			return
-		}
-
-		json, _ := json.Marshal(user)
-		res.Body = string(json)
-	})
+		return User{}, gohst.CreateHTTPError(404, "User not found")
+	})).Name("user")
 
 	server.AddHandler("POST /users", func(req *gohst.Request, res *gohst.Response) {
 		user := User{}
-		json.Unmarshal([]byte(req.Body), &user)
+		if err := req.DecodeJSON(&user); err != nil {
+			gohst.WriteJSONError(res, err)
+			return
+		}
+		if err := gohst.Validate(user); err != nil {
+			gohst.WriteJSONError(res, err)
+			return
+		}
 		user.Id = len(users) + 1
 		users = append(users, user)
 
-		json, _ := json.Marshal(user)
-		res.Body = string(json)
-		res.StatusCode = 201
+		// Link to the created user is built from the named route
+		location, _ := server.URL("user", map[string]string{"id": strconv.Itoa(user.Id)}, nil)
+		res.Headers.Set("Location", location)
+
+		res.JSON(201, user)
 	})
 
 	server.AddHandler("PUT /users/:id", func(req *gohst.Request, res *gohst.Response) {
 		id := req.Params["id"]
 		userId, err := strconv.Atoi(id)
 		if err != nil {
-			res.StatusCode = 400
-			json, _ := json.Marshal(Result{Message: "Invalid user id"})
-			res.Body = string(json)
+			res.JSON(400, Result{Message: "Invalid user id"})
 			return
 		}
 
 		user := User{}
-		json.Unmarshal([]byte(req.Body), &user)
+		if err := req.DecodeJSON(&user); err != nil {
+			gohst.WriteJSONError(res, err)
+			return
+		}
+		if err := gohst.Validate(user); err != nil {
+			gohst.WriteJSONError(res, err)
+			return
+		}
 		user.Id = userId
 
 		for i, u := range users {
@@ -112,17 +109,14 @@ func main() {
 			}
 		}
 
-		json, _ := json.Marshal(user)
-		res.Body = string(json)
+		res.JSON(200, user)
 	})
 
 	server.AddHandler("DELETE /users/:id", func(req *gohst.Request, res *gohst.Response) {
 		id := req.Params["id"]
 		userId, err := strconv.Atoi(id)
 		if err != nil {
-			res.StatusCode = 400
-			json, _ := json.Marshal(Result{Message: "Invalid user id"})
-			res.Body = string(json)
+			res.JSON(400, Result{Message: "Invalid user id"})
 			return
 		}
 
@@ -133,12 +127,11 @@ func main() {
 			}
 		}
 
-		json, _ := json.Marshal(Result{Message: "User deleted"})
-		res.Body = string(json)
+		res.JSON(200, Result{Message: "User deleted"})
 	})
 
 	server.AddHandler("GET /about", func(req *gohst.Request, res *gohst.Response) {
-		res.Headers["Content-Type"] = "text/html"
+		res.Headers.Set("Content-Type", "text/html")
 		res.Body = `
 		<body>
 			<h1>About</h1>
@@ -148,9 +141,7 @@ func main() {
 	})
 
 	server.AddHandler("/*", func(req *gohst.Request, res *gohst.Response) {
-		json, _ := json.Marshal(Result{Message: "Page not found"})
-		res.Body = string(json)
-		res.StatusCode = 404
+		res.JSON(404, Result{Message: "Page not found"})
 	})
 
 	stop, err := server.ListenAndServe(":8080")
@@ -13,7 +13,7 @@ func main() {
 	server.Use(func(next gohst.HandlerFunc) gohst.HandlerFunc {
 		return func(req *gohst.Request, res *gohst.Response) {
 
-			var raw string = req.Headers["Authorization"]
+			var raw string = req.Headers.Get("Authorization")
 
 			var bearer string = ""
 			var token string = ""
@@ -1,14 +1,25 @@
 package gohst
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/cccaaannn/gohst/src/websocket"
 )
 
 const (
@@ -29,7 +40,7 @@ const (
 )
 
 type result struct {
-	Query  map[string]string `json:"query"`
+	Query  Query             `json:"query"`
 	Params map[string]string `json:"params"`
 	Body   string            `json:"body"`
 }
@@ -50,10 +61,9 @@ func createAPIServer() *Server {
 		result := result{
 			Params: req.Params,
 			Query:  req.Query,
-			Body:   req.Body,
+			Body:   req.Body.String(),
 		}
-		jsonResponse, _ := json.Marshal(result)
-		res.Body = string(jsonResponse)
+		res.JSON(http.StatusOK, result)
 	})
 
 	return server
@@ -72,7 +82,7 @@ func createHTMLServer() *Server {
 	})
 
 	server.AddHandler("GET /api", func(req *Request, res *Response) {
-		res.Headers["Content-Type"] = "application/json"
+		res.Headers.Set("Content-Type", "application/json")
 		res.Body = ApiPageContent
 	})
 
@@ -97,7 +107,7 @@ func createMiddlewareServer() *Server {
 	server.Use(func(next HandlerFunc) HandlerFunc {
 		return func(req *Request, res *Response) {
 
-			var testHeader string = req.Headers[TestHeaderName]
+			var testHeader string = req.Headers.Get(TestHeaderName)
 
 			if testHeader == "" {
 				res.StatusCode = 401
@@ -170,7 +180,7 @@ func TestRequestParsing(t *testing.T) {
 
 	expectedResult := result{
 		Params: map[string]string{"param1": "test1", "param2": "5"},
-		Query:  map[string]string{"query1": "test2", "query2": "2"},
+		Query:  Query{"query1": {"test2"}, "query2": {"2"}},
 		Body:   ApiResponse,
 	}
 
@@ -484,3 +494,2025 @@ func TestMiddlewareRunOrder(t *testing.T) {
 		t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
 	}
 }
+
+func TestKeepAlive(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.SetMaxRequestsPerConnection(2)
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// When
+	for i, expectedClose := range []bool{false, true} {
+		_, err = conn.Write([]byte("GET /about HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		if err != nil {
+			t.Fatalf("Failed to send request %d: %v", i, err)
+		}
+
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("Failed to read response %d: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.Close != expectedClose {
+			t.Fatalf("Expected connection close %v, got %v", expectedClose, resp.Close)
+		}
+		if AboutPageContent != string(body) {
+			t.Fatalf("Expected response body %v, got %v", AboutPageContent, string(body))
+		}
+	}
+
+	// Connection is closed after reaching the max requests per connection
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("Expected connection to be closed, got %v", err)
+	}
+}
+
+func TestResponsesWithoutBody(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.AddHandler("DELETE /items", func(req *Request, res *Response) {
+		res.StatusCode = http.StatusNoContent
+		res.Body = "deleted"
+	})
+	server.AddHandler("GET /cached", func(req *Request, res *Response) {
+		res.StatusCode = http.StatusNotModified
+		res.Body = "cached"
+	})
+	server.AddHandler("GET /stream", func(req *Request, res *Response) {
+		res.StatusCode = http.StatusNoContent
+		res.WriteString("x")
+		res.Flush()
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	cases := []struct {
+		request       string
+		statusCode    int
+		contentLength string
+		body          string
+	}{
+		{request: "DELETE /items HTTP/1.1\r\nHost: localhost\r\n\r\n", statusCode: http.StatusNoContent},
+		{request: "GET /cached HTTP/1.1\r\nHost: localhost\r\n\r\n", statusCode: http.StatusNotModified, contentLength: "6"},
+		{request: "GET /stream HTTP/1.1\r\nHost: localhost\r\n\r\n", statusCode: http.StatusNoContent},
+		{request: "GET /about HTTP/1.1\r\nHost: localhost\r\n\r\n", statusCode: http.StatusOK, contentLength: fmt.Sprint(len(AboutPageContent)), body: AboutPageContent},
+	}
+
+	// When
+	for _, c := range cases {
+		_, err = conn.Write([]byte(c.request))
+		if err != nil {
+			t.Fatalf("Failed to send request %q: %v", c.request, err)
+		}
+
+		// Bodies sent after a 204 or 304 would be read as the start of the next response
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("Failed to read response of %q: %v", c.request, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %q, got %v", c.statusCode, c.request, resp.StatusCode)
+		}
+		if resp.Header.Get("Content-Length") != c.contentLength {
+			t.Fatalf("Expected content length %q for %q, got %q", c.contentLength, c.request, resp.Header.Get("Content-Length"))
+		}
+		if string(body) != c.body {
+			t.Fatalf("Expected response body %v for %q, got %v", c.body, c.request, string(body))
+		}
+	}
+}
+
+func TestContentLengthWithTransferEncoding(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.AddHandler("POST /echo", func(req *Request, res *Response) {
+		res.Body = req.Body.String()
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// When
+	_, err = conn.Write([]byte("" +
+		"POST /echo HTTP/1.1\r\nHost: localhost\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"5\r\nHello\r\n0\r\n\r\n" +
+		"GET /about HTTP/1.1\r\nHost: localhost\r\n\r\n",
+	))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// Then
+	if string(body) != "Hello" {
+		t.Fatalf("Expected response body Hello, got %v", string(body))
+	}
+	if !resp.Close {
+		t.Fatalf("Expected connection close")
+	}
+
+	// Pipelined request is not served
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Fatalf("Expected connection to be closed, got %v", err)
+	}
+}
+
+func TestHTTP10Close(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// When
+	_, err = conn.Write([]byte("GET /about HTTP/1.0\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	raw, err := io.ReadAll(conn)
+
+	// Then
+	if err != nil {
+		t.Fatalf("Expected connection to be closed, got %v", err)
+	}
+	if !strings.Contains(string(raw), "Connection: close") {
+		t.Fatalf("Expected connection close header, got %v", string(raw))
+	}
+}
+
+func TestChunkedRequestBody(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	server.AddHandler("POST /echo", func(req *Request, res *Response) {
+		// Trailers arrive after the body
+		res.Body = req.Body.String()
+		res.Headers.Set(TestHeaderName, req.Trailers.Get(TestHeaderName))
+		extensions := make([]string, 0, len(req.ChunkExtensions))
+		for _, extension := range req.ChunkExtensions {
+			extensions = append(extensions, extension.Name+"="+extension.Value)
+		}
+		res.Headers.Set("Chunk-Extensions", strings.Join(extensions, "|"))
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// When
+	_, err = conn.Write([]byte("" +
+		"POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"5;name=\"value\"\r\nHello\r\n" +
+		"8 ; quoted = \"a;b\\\"c\\q\" ;flag;token=x\r\n, World!\r\n" +
+		"0\r\n" +
+		TestHeaderName + ": " + TestHeaderContent1 + "\r\n\r\n",
+	))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusOK
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	if resp.Header.Get(TestHeaderName) != TestHeaderContent1 {
+		t.Fatalf("Expected trailer %v, got %v", TestHeaderContent1, resp.Header.Get(TestHeaderName))
+	}
+
+	// Quoted values may contain ";" and a backslash escapes any character
+	expectedExtensions := `name=value|quoted=a;b"cq|flag=|token=x`
+	if resp.Header.Get("Chunk-Extensions") != expectedExtensions {
+		t.Fatalf("Expected chunk extensions %v, got %v", expectedExtensions, resp.Header.Get("Chunk-Extensions"))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	expectedBody := "Hello, World!"
+	if expectedBody != string(body) {
+		t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
+	}
+}
+
+func TestMalformedChunkedRequestBody(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	server.AddHandler("POST /echo", func(req *Request, res *Response) {
+		if body, err := req.Body.Bytes(); err == nil {
+			res.SetBytes(body)
+		}
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	bodies := []string{
+		"zz\r\nHello\r\n0\r\n\r\n",
+		"5;name=\"value\r\nHello\r\n0\r\n\r\n",
+		"5;name=\"a\"b\r\nHello\r\n0\r\n\r\n",
+		"5;=value\r\nHello\r\n0\r\n\r\n",
+		"5;name=\r\nHello\r\n0\r\n\r\n",
+		"5;na me\r\nHello\r\n0\r\n\r\n",
+	}
+
+	for _, body := range bodies {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		// When
+		_, err = conn.Write([]byte("POST /echo HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n" + body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		resp.Body.Close()
+		conn.Close()
+
+		// Then
+		expectedStatusCode := http.StatusBadRequest
+		if resp.StatusCode != expectedStatusCode {
+			t.Fatalf("Expected status code %v for %q, got %v", expectedStatusCode, body, resp.StatusCode)
+		}
+	}
+}
+
+func TestStreamingResponse(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	server.AddHandler("GET /stream", func(req *Request, res *Response) {
+		res.Trailers.Set(TestHeaderName, TestHeaderContent1)
+		for _, part := range []string{TestHeaderContent1, TestHeaderContent2, TestHeaderContent3} {
+			res.WriteString(part)
+			res.Flush()
+		}
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	// When
+	resp, err := http.Get(
+		fmt.Sprintf(
+			"%s:%s/stream",
+			ServerHost,
+			ServerPort,
+		),
+	)
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusOK
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Fatalf("Expected chunked transfer encoding, got %v", resp.TransferEncoding)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	expectedBody := fmt.Sprintf("%s%s%s", TestHeaderContent1, TestHeaderContent2, TestHeaderContent3)
+	if expectedBody != string(body) {
+		t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
+	}
+
+	if resp.Trailer.Get(TestHeaderName) != TestHeaderContent1 {
+		t.Fatalf("Expected trailer %v, got %v", TestHeaderContent1, resp.Trailer.Get(TestHeaderName))
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	// Given
+	setup()
+	disconnected := make(chan struct{})
+	server := createAPIServer()
+	server.AddHandler("GET /events", func(req *Request, res *Response) {
+		stream, err := res.EventStream()
+		if err != nil {
+			return
+		}
+		// Intervals that are not positive send no heartbeat and leave room for one that does
+		stream.Heartbeat(0)
+		stream.Heartbeat(100 * time.Millisecond)
+		stream.Send(Event{ID: req.LastEventID(), Event: "fruit", Data: TestHeaderContent1 + "\n" + TestHeaderContent2})
+
+		<-stream.Done()
+		close(disconnected)
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	// When
+	req, err := http.NewRequest("GET",
+		fmt.Sprintf(
+			"%s:%s/events",
+			ServerHost,
+			ServerPort,
+		),
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create GET request: %v", err)
+	}
+	req.Header.Add("Last-Event-ID", "5")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send GET request: %v", err)
+	}
+
+	// Then
+	expectedContentType := "text/event-stream"
+	if resp.Header.Get("Content-Type") != expectedContentType {
+		t.Fatalf("Expected content type %v, got %v", expectedContentType, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	var event strings.Builder
+	for !strings.HasSuffix(event.String(), "\n\n") {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if !strings.HasPrefix(line, ":") {
+			event.WriteString(line)
+		}
+	}
+
+	expectedEvent := fmt.Sprintf("id: 5\nevent: fruit\ndata: %s\ndata: %s\n\n", TestHeaderContent1, TestHeaderContent2)
+	if event.String() != expectedEvent {
+		t.Fatalf("Expected event %q, got %q", expectedEvent, event.String())
+	}
+
+	resp.Body.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected handler to detect the client disconnect")
+	}
+}
+
+func TestEventStreamWithRequestBody(t *testing.T) {
+	// Given
+	setup()
+	received := make(chan string, 1)
+	server := createAPIServer()
+	server.AddHandler("POST /events", func(req *Request, res *Response) {
+		stream, err := res.EventStream()
+		if err != nil {
+			return
+		}
+		stream.Send(Event{Data: TestHeaderContent1})
+
+		// Body is not read before the stream starts, it is still there after the client leaves
+		<-stream.Done()
+		received <- req.Body.String()
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	// When
+	_, err = conn.Write([]byte("POST /events HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nHello"))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "data: "+TestHeaderContent1+"\n" {
+		t.Fatalf("Expected event, got %q %v", line, err)
+	}
+	conn.Close()
+
+	// Then
+	select {
+	case body := <-received:
+		if body != "Hello" {
+			t.Fatalf("Expected request body to be kept, got %q", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected handler to detect the client disconnect")
+	}
+}
+
+func writeMaskedFrame(conn net.Conn, header byte, payload []byte) error {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{header, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	return err
+}
+
+func readFrame(reader *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, header[1]&0x7F)
+	_, err := io.ReadFull(reader, payload)
+	return header[0], payload, err
+}
+
+func TestWebSocket(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	server.AddHandler("GET /ws", func(req *Request, res *Response) {
+		conn, err := websocket.Upgrade(req, res, &websocket.Options{Subprotocols: []string{"chat"}})
+		if err != nil {
+			return
+		}
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, message)
+		}
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// When
+	_, err = conn.Write([]byte("" +
+		"GET /ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Protocol: other, chat\r\n\r\n",
+	))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	// Then
+	expectedStatusCode := http.StatusSwitchingProtocols
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	expectedAccept := "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+	if resp.Header.Get("Sec-WebSocket-Accept") != expectedAccept {
+		t.Fatalf("Expected accept key %v, got %v", expectedAccept, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	if resp.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("Expected subprotocol chat, got %v", resp.Header.Get("Sec-WebSocket-Protocol"))
+	}
+
+	// Fragmented text message with a ping in between is echoed back as a single frame
+	writeMaskedFrame(conn, 0x01, []byte(TestHeaderContent1))
+	writeMaskedFrame(conn, 0x89, []byte(TestHeaderContent3))
+	writeMaskedFrame(conn, 0x80, []byte(TestHeaderContent2))
+
+	header, payload, err := readFrame(reader)
+	if err != nil || header != 0x8A || string(payload) != TestHeaderContent3 {
+		t.Fatalf("Expected pong %v, got %x %v %v", TestHeaderContent3, header, string(payload), err)
+	}
+
+	header, payload, err = readFrame(reader)
+	expectedMessage := TestHeaderContent1 + TestHeaderContent2
+	if err != nil || header != 0x81 || string(payload) != expectedMessage {
+		t.Fatalf("Expected message %v, got %x %v %v", expectedMessage, header, string(payload), err)
+	}
+
+	// Close handshake is answered with the same code
+	writeMaskedFrame(conn, 0x88, []byte{0x03, 0xE8})
+	header, payload, err = readFrame(reader)
+	if err != nil || header != 0x88 || len(payload) != 2 || payload[0] != 0x03 || payload[1] != 0xE8 {
+		t.Fatalf("Expected close frame, got %x %v %v", header, payload, err)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	// When
+	resp, err := http.Post(
+		fmt.Sprintf(
+			"%s:%s/tls",
+			ServerHost,
+			ServerPort,
+		),
+		"application/json",
+		bytes.NewReader([]byte(ApiResponse)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusMethodNotAllowed
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	expectedAllow := "GET, HEAD, OPTIONS"
+	if resp.Header.Get("Allow") != expectedAllow {
+		t.Fatalf("Expected allow header %v, got %v", expectedAllow, resp.Header.Get("Allow"))
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.AddHandler("POST /about", func(req *Request, res *Response) {})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	// When
+	req, err := http.NewRequest("OPTIONS",
+		fmt.Sprintf(
+			"%s:%s/about",
+			ServerHost,
+			ServerPort,
+		),
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create OPTIONS request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send OPTIONS request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusNoContent
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	expectedAllow := "GET, HEAD, OPTIONS, POST"
+	if resp.Header.Get("Allow") != expectedAllow {
+		t.Fatalf("Expected allow header %v, got %v", expectedAllow, resp.Header.Get("Allow"))
+	}
+}
+
+func TestHeadRequest(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// When
+	_, err = conn.Write([]byte("HEAD /about HTTP/1.1\r\nHost: localhost\r\n\r\nGET /about HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to send requests: %v", err)
+	}
+	headResp, err := http.ReadResponse(reader, &http.Request{Method: "HEAD"})
+	if err != nil {
+		t.Fatalf("Failed to read HEAD response: %v", err)
+	}
+	headResp.Body.Close()
+	getResp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read GET response: %v", err)
+	}
+	defer getResp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusOK
+	if headResp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, headResp.StatusCode)
+	}
+
+	expectedContentLength := fmt.Sprintf("%d", len(AboutPageContent))
+	if headResp.Header.Get("Content-Length") != expectedContentLength {
+		t.Fatalf("Expected content length %v, got %v", expectedContentLength, headResp.Header.Get("Content-Length"))
+	}
+
+	// Body of the GET response starts right after the HEAD response headers
+	body, _ := io.ReadAll(getResp.Body)
+	if AboutPageContent != string(body) {
+		t.Fatalf("Expected response body %v, got %v", AboutPageContent, string(body))
+	}
+}
+
+func appendBodyMiddleware(text string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request, res *Response) {
+			res.Body += text
+			next(req, res)
+		}
+	}
+}
+
+func createGroupServer() *Server {
+	server := CreateServer()
+	server.Use(appendBodyMiddleware("server-"))
+
+	server.AddHandler("GET /health", func(req *Request, res *Response) {
+		res.Body += "health"
+	})
+
+	api := server.Group("/api", appendBodyMiddleware("api-"))
+	v1 := api.Group("/v1/")
+	v1.Use(appendBodyMiddleware("v1-"))
+	v1.AddHandler("GET /users/:id", func(req *Request, res *Response) {
+		res.Body += "user" + req.Params["id"]
+	}, appendBodyMiddleware("route-"))
+
+	return server
+}
+
+func TestRouteGroups(t *testing.T) {
+	// Given
+	setup()
+	server := createGroupServer()
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := map[string]string{
+		"/health":         "server-health",
+		"/api/v1/users/5": "server-api-v1-route-user5",
+	}
+
+	for path, expectedBody := range cases {
+		// When
+		resp, err := http.Get(
+			fmt.Sprintf(
+				"%s:%s%s",
+				ServerHost,
+				ServerPort,
+				path,
+			),
+		)
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		expectedStatusCode := http.StatusOK
+		if resp.StatusCode != expectedStatusCode {
+			t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+		}
+
+		if expectedBody != string(body) {
+			t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	// Given
+	server := createAPIServer()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	// When
+	resp, err := http.Post(
+		fmt.Sprintf("%s/path1/test1/path2/5?query1=test2&query2=2", httpServer.URL),
+		"application/json",
+		bytes.NewReader([]byte(ApiResponse)),
+	)
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusOK
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	expectedContentType := "application/json"
+	if resp.Header.Get("Content-Type") != expectedContentType {
+		t.Fatalf("Expected content type %v, got %v", expectedContentType, resp.Header.Get("Content-Type"))
+	}
+
+	expectedResult := result{
+		Params: map[string]string{"param1": "test1", "param2": "5"},
+		Query:  Query{"query1": {"test2"}, "query2": {"2"}},
+		Body:   ApiResponse,
+	}
+
+	expectedJson, _ := json.Marshal(expectedResult)
+	body, _ := io.ReadAll(resp.Body)
+	if string(expectedJson) != string(body) {
+		t.Fatalf("Expected response body %v, got %v", string(expectedJson), string(body))
+	}
+}
+
+func TestWrapHTTPHandler(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.AddHandler("POST /std/:name", WrapHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set(TestHeaderName, r.Header.Get(TestHeaderName))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s", r.URL.Path, r.URL.Query().Get("q"), string(body))
+	})))
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	// When
+	req, err := http.NewRequest("POST",
+		fmt.Sprintf(
+			"%s:%s/std/banana?q=melon",
+			ServerHost,
+			ServerPort,
+		),
+		strings.NewReader(TestHeaderContent3),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create POST request: %v", err)
+	}
+	req.Header.Add(TestHeaderName, TestHeaderContent1)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send POST request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	expectedStatusCode := http.StatusCreated
+	if resp.StatusCode != expectedStatusCode {
+		t.Fatalf("Expected status code %v, got %v", expectedStatusCode, resp.StatusCode)
+	}
+
+	if resp.Header.Get(TestHeaderName) != TestHeaderContent1 {
+		t.Fatalf("Expected header %v, got %v", TestHeaderContent1, resp.Header.Get(TestHeaderName))
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	expectedBody := "/std/banana melon apple"
+	if expectedBody != string(body) {
+		t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	// Given
+	setup()
+	started := make(chan struct{})
+	server := createAPIServer()
+	server.AddHandler("GET /slow", func(req *Request, res *Response) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		res.Body = ApiResponse
+	})
+	_, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	type response struct {
+		statusCode int
+		body       string
+		err        error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("%s:%s/slow", ServerHost, ServerPort))
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- response{statusCode: resp.StatusCode, body: string(body)}
+	}()
+	<-started
+
+	// When
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err = server.Shutdown(ctx)
+
+	// Then
+	if err != nil {
+		t.Fatalf("Expected graceful shutdown, got %v", err)
+	}
+	if err := server.Wait(); err != nil {
+		t.Fatalf("Expected no final error, got %v", err)
+	}
+
+	resp := <-responses
+	if resp.err != nil {
+		t.Fatalf("Expected in-flight request to finish, got %v", resp.err)
+	}
+	if resp.statusCode != http.StatusOK || resp.body != ApiResponse {
+		t.Fatalf("Expected in-flight response %v, got %v %v", ApiResponse, resp.statusCode, resp.body)
+	}
+
+	if _, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort)); err == nil {
+		t.Fatalf("Expected server to stop accepting connections")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	// Given
+	setup()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := createAPIServer()
+	server.AddHandler("GET /hang", func(req *Request, res *Response) {
+		close(started)
+		<-release
+	})
+	_, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("%s:%s/hang", ServerHost, ServerPort))
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-started
+
+	// When
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = server.Shutdown(ctx)
+
+	// Then
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if err := server.Wait(); err != context.DeadlineExceeded {
+		t.Fatalf("Expected final error deadline exceeded, got %v", err)
+	}
+	if err := <-requestErr; err == nil {
+		t.Fatalf("Expected hanging request to be closed")
+	}
+}
+
+func TestCloseDuringShutdown(t *testing.T) {
+	// Given
+	setup()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := createAPIServer()
+	server.AddHandler("GET /hang", func(req *Request, res *Response) {
+		close(started)
+		<-release
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("%s:%s/hang", ServerHost, ServerPort))
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-started
+
+	// Shutdown without a deadline waits for the hanging handler
+	close(stop)
+	time.Sleep(200 * time.Millisecond)
+
+	// When
+	err = server.Close()
+
+	// Then
+	if err != nil {
+		t.Fatalf("Expected close to force the running shutdown, got %v", err)
+	}
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected server to be stopped after close")
+	}
+	if err := server.Wait(); err != ErrServerClosed {
+		t.Fatalf("Expected final error server closed, got %v", err)
+	}
+	if err := <-requestErr; err == nil {
+		t.Fatalf("Expected hanging request to be closed")
+	}
+	if err := server.Close(); err != ErrServerClosed {
+		t.Fatalf("Expected closing a stopped server to fail, got %v", err)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	// Given
+	setup()
+	server := createHTMLServer()
+	server.SetReadHeaderTimeout(300 * time.Millisecond)
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	slowConn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer slowConn.Close()
+	silentConn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer silentConn.Close()
+
+	// When
+	_, err = slowConn.Write([]byte("GET /about HTTP/1.1\r\nHost: localhost\r\n"))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	slowConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	slowRaw, slowErr := io.ReadAll(slowConn)
+	silentConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	silentRaw, silentErr := io.ReadAll(silentConn)
+
+	// Then
+	if slowErr != nil {
+		t.Fatalf("Expected slow connection to be closed, got %v", slowErr)
+	}
+	if !strings.HasPrefix(string(slowRaw), "HTTP/1.1 408") {
+		t.Fatalf("Expected request timeout, got %v", string(slowRaw))
+	}
+	if silentErr != nil {
+		t.Fatalf("Expected silent connection to be closed, got %v", silentErr)
+	}
+	if len(silentRaw) != 0 {
+		t.Fatalf("Expected no response for silent connection, got %v", string(silentRaw))
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.SetLimits(Limits{MaxRequestLineLength: 64, MaxHeaderBytes: 256, MaxHeaderCount: 3, MaxBodySize: 8})
+	echo := func(req *Request, res *Response) {
+		// Bodies that fail to read are left for the server to answer
+		if body, err := req.Body.Bytes(); err == nil {
+			res.SetBytes(body)
+		}
+	}
+	server.AddHandler("POST /small", echo)
+	server.AddHandler("POST /upload", echo).SetLimits(Limits{MaxBodySize: 64})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		request    string
+		statusCode int
+	}{
+		{request: "POST /small HTTP/1.1\r\nContent-Length: 5\r\n\r\nHello", statusCode: http.StatusOK},
+		{request: "POST /small?" + strings.Repeat("a", 64) + " HTTP/1.1\r\n\r\n", statusCode: http.StatusRequestURITooLong},
+		{request: "POST /small HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\nD: 4\r\n\r\n", statusCode: http.StatusRequestHeaderFieldsTooLarge},
+		{request: "POST /small HTTP/1.1\r\nA: " + strings.Repeat("a", 256) + "\r\n\r\n", statusCode: http.StatusRequestHeaderFieldsTooLarge},
+		{request: "POST /small HTTP/1.1\r\nContent-Length: 1000000000\r\n\r\n", statusCode: http.StatusRequestEntityTooLarge},
+		{request: "POST /upload HTTP/1.1\r\nContent-Length: 20\r\n\r\n" + strings.Repeat("a", 20), statusCode: http.StatusOK},
+		{request: "POST /upload HTTP/1.1\r\nContent-Length: 65\r\n\r\n", statusCode: http.StatusRequestEntityTooLarge},
+		{request: "POST /small HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n9\r\n" + strings.Repeat("a", 9) + "\r\n0\r\n\r\n", statusCode: http.StatusRequestEntityTooLarge},
+		{request: "POST /small HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1\r\na\r\n7fffffffffffffff\r\n" + strings.Repeat("a", 4096), statusCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		// When
+		_, err = conn.Write([]byte(c.request))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		resp.Body.Close()
+		conn.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %q, got %v", c.statusCode, c.request, resp.StatusCode)
+		}
+	}
+}
+
+func TestMalformedRequests(t *testing.T) {
+	// Given
+	setup()
+	server := createAPIServer()
+	server.SetErrorHandler(func(res *Response, err error) {
+		res.Body = fmt.Sprintf(`{"status":%d}`, res.StatusCode)
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		request    string
+		statusCode int
+	}{
+		{request: "GET /tls\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "GET  /tls HTTP/1.1\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "GET /tls HTTP/2.0\r\n\r\n", statusCode: http.StatusHTTPVersionNotSupported},
+		{request: "GET /tls HTTP/1.1\r\nHost localhost\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "GET /tls HTTP/1.1\r\nHost : localhost\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "POST /tls HTTP/1.1\r\nContent-Length: -1\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "POST /tls HTTP/1.1\r\nContent-Length: 1, 2\r\n\r\n", statusCode: http.StatusBadRequest},
+		{request: "POST /tls HTTP/1.1\r\nTransfer-Encoding: gzip\r\n\r\n", statusCode: http.StatusNotImplemented},
+		{request: "POST /tls HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n", statusCode: http.StatusNotImplemented},
+		{request: "POST /tls HTTP/1.1\r\nTransfer-Encoding: chunked, chunked\r\n\r\n", statusCode: http.StatusNotImplemented},
+	}
+
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		// When
+		_, err = conn.Write([]byte(c.request))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read response for %q: %v", c.request, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		conn.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %q, got %v", c.statusCode, c.request, resp.StatusCode)
+		}
+		expectedBody := fmt.Sprintf(`{"status":%d}`, c.statusCode)
+		if string(body) != expectedBody {
+			t.Fatalf("Expected body %v for %q, got %v", expectedBody, c.request, string(body))
+		}
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("POST /headers", func(req *Request, res *Response) {
+		res.Body = fmt.Sprintf("%s|%s|%s", req.Headers.Get("Content-Type"), req.Headers.Join("Accept"), req.Body.String())
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// When
+	_, err = conn.Write([]byte("" +
+		"POST /headers HTTP/1.1\r\n" +
+		"content-type:text/plain\r\n" +
+		"accept: text/html\r\n" +
+		"Accept:\t application/json \r\n" +
+		"content-length: 5\r\n\r\n" +
+		"Hello",
+	))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Then
+	body, _ := io.ReadAll(resp.Body)
+	expectedBody := "text/plain|text/html, application/json|Hello"
+	if string(body) != expectedBody {
+		t.Fatalf("Expected response body %v, got %v", expectedBody, string(body))
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("GET /cookies", func(req *Request, res *Response) {
+		res.Headers.Set("X-B", "b")
+		res.Headers.Add("Set-Cookie", "a=1")
+		res.Headers.Add("Set-Cookie", "b=2")
+		res.Headers.Set("X-A", "a")
+	})
+	server.AddHandler("GET /injection", func(req *Request, res *Response) {
+		res.Headers.Set("X-Name", "name\r\nInjected: true")
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	readHead := func(path string) []string {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer conn.Close()
+
+		_, err = conn.Write([]byte(fmt.Sprintf("GET %s HTTP/1.1\r\nConnection: close\r\n\r\n", path)))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		raw, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		head, _, _ := strings.Cut(string(raw), "\r\n\r\n")
+		return strings.Split(head, "\r\n")
+	}
+
+	// When
+	lines := readHead("/cookies")
+	injectionLines := readHead("/injection")
+
+	// Then
+	names := make([]string, 0)
+	for _, line := range lines[1:] {
+		name, _, _ := strings.Cut(line, ":")
+		names = append(names, name)
+	}
+	expectedNames := "[Connection Content-Length Content-Type Date Server Set-Cookie Set-Cookie X-A X-B]"
+	if fmt.Sprint(names) != expectedNames {
+		t.Fatalf("Expected header order %v, got %v", expectedNames, names)
+	}
+	if !slices.Contains(lines, "Set-Cookie: a=1") || !slices.Contains(lines, "Set-Cookie: b=2") {
+		t.Fatalf("Expected both cookies as separate headers, got %v", lines)
+	}
+
+	if injectionLines[0] != "HTTP/1.1 500 Internal Server Error" {
+		t.Fatalf("Expected internal server error, got %v", injectionLines[0])
+	}
+	for _, line := range injectionLines {
+		if strings.HasPrefix(line, "Injected") || strings.HasPrefix(line, "X-Name") {
+			t.Fatalf("Expected invalid header not to be sent, got %v", injectionLines)
+		}
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.SetPathOptions(PathOptions{Clean: true, TrailingSlashRedirect: http.StatusPermanentRedirect})
+	server.AddHandler("GET /files/:name", func(req *Request, res *Response) {
+		res.Body = fmt.Sprintf("%s|%s|%s", req.Params["name"], req.Path, req.RawPath)
+	})
+	server.AddHandler("GET /users", func(req *Request, res *Response) {
+		res.Body = "users"
+	})
+	server.AddHandler("GET /static/*filepath", func(req *Request, res *Response) {
+		res.Body = "file " + req.Params["filepath"]
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		path       string
+		statusCode int
+		body       string
+		location   string
+	}{
+		{path: "//files/./my%20doc", statusCode: http.StatusOK, body: "my doc|/files/my%20doc|//files/./my%20doc"},
+		{path: "/files/a%2Fb", statusCode: http.StatusOK, body: "a/b|/files/a%2Fb|/files/a%2Fb"},
+		{path: "/files/../users", statusCode: http.StatusOK, body: "users"},
+		{path: "/users/?page=2", statusCode: http.StatusPermanentRedirect, location: "/users?page=2"},
+		{path: "/files/a/", statusCode: http.StatusPermanentRedirect, location: "/files/a"},
+		{path: "/other/", statusCode: http.StatusNotFound},
+		{path: "/static/..%2F..%2Fetc%2Fpasswd", statusCode: http.StatusBadRequest},
+		{path: "/files/..%2F..%2Fetc", statusCode: http.StatusBadRequest},
+		{path: "/static/css/%2e%2e/app.js", statusCode: http.StatusOK, body: "file app.js"},
+	}
+
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		// When
+		_, err = conn.Write([]byte(fmt.Sprintf("GET %s HTTP/1.1\r\n\r\n", c.path)))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		conn.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %v, got %v", c.statusCode, c.path, resp.StatusCode)
+		}
+		if c.body != "" && string(body) != c.body {
+			t.Fatalf("Expected response body %v for %v, got %v", c.body, c.path, string(body))
+		}
+		if resp.Header.Get("Location") != c.location {
+			t.Fatalf("Expected location %v for %v, got %v", c.location, c.path, resp.Header.Get("Location"))
+		}
+	}
+}
+
+func TestRoutePatterns(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("GET /static/*filepath", func(req *Request, res *Response) {
+		res.Body = "file " + req.Params["filepath"]
+	})
+	server.AddHandler("GET /users/:id<int>", func(req *Request, res *Response) {
+		res.Body = "id " + req.Params["id"]
+	})
+	server.AddHandler("GET /users/:name", func(req *Request, res *Response) {
+		res.Body = "name " + req.Params["name"]
+	})
+	server.AddHandler("GET /posts/:page?", func(req *Request, res *Response) {
+		res.Body = "page " + req.Params["page"]
+	})
+	server.AddHandler("GET /docs/:title{[^0-9]+}", func(req *Request, res *Response) {
+		res.Body = "title " + req.Params["title"]
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := map[string]string{
+		"/static/css/my%20site.css": "file css/my site.css",
+		"/users/42":                 "id 42",
+		"/users/bob":                "name bob",
+		"/posts":                    "page ",
+		"/posts/3":                  "page 3",
+		"/docs/my%20doc":            "title my doc",
+	}
+
+	for path, expectedBody := range cases {
+		// When
+		resp, err := http.Get(fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, path))
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if string(body) != expectedBody {
+			t.Fatalf("Expected response body %v for %v, got %v", expectedBody, path, string(body))
+		}
+	}
+}
+
+func TestReverseRouting(t *testing.T) {
+	// Given
+	server := CreateServer()
+	api := server.Group("/api")
+	api.AddHandler("GET /users/:id<int>", func(req *Request, res *Response) {}).Name("user")
+	server.AddHandler("GET /files/*filepath", func(req *Request, res *Response) {}).Name("file")
+
+	// When
+	userURL, userErr := server.URL("user", map[string]string{"id": "5"}, Query{"tab": {"posts"}, "q": {"a b"}})
+	fileURL, fileErr := server.URL("file", map[string]string{"filepath": "docs/my file.txt"}, nil)
+	_, invalidErr := server.URL("user", map[string]string{"id": "me"}, nil)
+	_, unknownErr := server.URL("other", nil, nil)
+
+	// Then
+	if userErr != nil || userURL != "/api/users/5?q=a+b&tab=posts" {
+		t.Fatalf("Expected user url, got %v %v", userURL, userErr)
+	}
+	if fileErr != nil || fileURL != "/files/docs/my%20file.txt" {
+		t.Fatalf("Expected file url, got %v %v", fileURL, fileErr)
+	}
+	if invalidErr == nil {
+		t.Fatalf("Expected error for a param violating its constraint")
+	}
+	if unknownErr == nil {
+		t.Fatalf("Expected error for an unknown route name")
+	}
+}
+
+func TestReverseRoutingRoundTrip(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	echo := func(req *Request, res *Response) {
+		res.JSON(http.StatusOK, req.Params)
+	}
+	server.AddHandler("GET /docs/:name{[^0-9]+}", echo).Name("doc")
+	server.AddHandler("GET /users/:id<int>/:tab?", echo).Name("user")
+	server.AddHandler("GET /static/*filepath", echo).Name("static")
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		name   string
+		params map[string]string
+	}{
+		{name: "doc", params: map[string]string{"name": "my doc"}},
+		{name: "doc", params: map[string]string{"name": "a/b?c"}},
+		{name: "user", params: map[string]string{"id": "5"}},
+		{name: "user", params: map[string]string{"id": "5", "tab": "new posts"}},
+		{name: "static", params: map[string]string{"filepath": "css/my site.css"}},
+	}
+
+	for _, c := range cases {
+		// When
+		path, err := server.URL(c.name, c.params, nil)
+		if err != nil {
+			t.Fatalf("Failed to build url of %v: %v", c.name, err)
+		}
+		resp, err := http.Get(fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, path))
+		if err != nil {
+			t.Fatalf("Failed to send GET request: %v", err)
+		}
+		params := map[string]string{}
+		json.NewDecoder(resp.Body).Decode(&params)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %v built for %v to route, got %v", path, c.name, resp.StatusCode)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(c.params) {
+			t.Fatalf("Expected params %v from %v, got %v", c.params, path, params)
+		}
+	}
+}
+
+func TestBinaryBodies(t *testing.T) {
+	// Given
+	setup()
+	data := []byte{0x00, 0xff, 0x1f, 0x8b, '\r', '\n', 0x00, 0x80}
+	server := CreateServer()
+	server.AddHandler("POST /echo", func(req *Request, res *Response) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			res.StatusCode = http.StatusBadRequest
+			return
+		}
+		res.SetBytes(body)
+	})
+	server.AddHandler("GET /known", func(req *Request, res *Response) {
+		res.SetReader(bytes.NewReader(data), int64(len(data)))
+	})
+	server.AddHandler("GET /unknown", func(req *Request, res *Response) {
+		res.SetReader(io.NopCloser(bytes.NewReader(data)), -1)
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		method        string
+		path          string
+		contentLength int64
+	}{
+		{method: "POST", path: "/echo", contentLength: int64(len(data))},
+		{method: "GET", path: "/known", contentLength: int64(len(data))},
+		{method: "GET", path: "/unknown", contentLength: -1},
+	}
+
+	for _, c := range cases {
+		// When
+		req, _ := http.NewRequest(c.method, fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, c.path), bytes.NewReader(data))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if err != nil || !bytes.Equal(body, data) {
+			t.Fatalf("Expected body %v for %s, got %v %v", data, c.path, body, err)
+		}
+		if resp.ContentLength != c.contentLength {
+			t.Fatalf("Expected content length %v for %s, got %v", c.contentLength, c.path, resp.ContentLength)
+		}
+	}
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+type message struct {
+	Message string `json:"message"`
+}
+
+func TestJSONHelpers(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("POST /greet", JSONHandler(func(req *Request, in greeting) (message, error) {
+		if in.Name == "" {
+			return message{}, CreateHTTPError(http.StatusUnprocessableEntity, "name is required")
+		}
+		if in.Name == "panic" {
+			return message{}, fmt.Errorf("secret details")
+		}
+		if in.Name == "slow" {
+			return message{}, fmt.Errorf("query: %w", context.DeadlineExceeded)
+		}
+		return message{Message: "Hello " + in.Name}, nil
+	}))
+	server.AddHandler("POST /strict", func(req *Request, res *Response) {
+		in := greeting{}
+		if err := req.DecodeJSONWith(&in, JSONOptions{DisallowUnknownFields: true, MaxSize: 32}); err != nil {
+			res.StatusCode = http.StatusBadRequest
+			res.Body = err.Error()
+			return
+		}
+		res.JSON(http.StatusCreated, in)
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		path        string
+		contentType string
+		body        string
+		statusCode  int
+		response    string
+	}{
+		{path: "/greet", contentType: "application/json", body: `{"name":"can"}`, statusCode: http.StatusOK, response: `{"message":"Hello can"}`},
+		{path: "/greet", contentType: "application/json; charset=utf-8", body: `{"name":"can","age":3}`, statusCode: http.StatusOK, response: `{"message":"Hello can"}`},
+		{path: "/greet", contentType: "text/plain", body: `{"name":"can"}`, statusCode: http.StatusUnsupportedMediaType},
+		{path: "/greet", contentType: "application/json", body: `{"name":`, statusCode: http.StatusBadRequest},
+		{path: "/greet", contentType: "application/json", body: `{"name":"a"} {}`, statusCode: http.StatusBadRequest},
+		{path: "/greet", contentType: "application/json", body: `{}`, statusCode: http.StatusUnprocessableEntity, response: `{"error":"name is required"}`},
+		{path: "/greet", contentType: "application/json", body: `{"name":"panic"}`, statusCode: http.StatusInternalServerError, response: `{"error":"internal server error"}`},
+		{path: "/greet", contentType: "application/json", body: `{"name":"slow"}`, statusCode: http.StatusInternalServerError, response: `{"error":"internal server error"}`},
+		{path: "/strict", contentType: "application/json", body: `{"name":"can"}`, statusCode: http.StatusCreated, response: `{"name":"can"}`},
+		{path: "/strict", contentType: "application/json", body: `{"name":"can","age":3}`, statusCode: http.StatusBadRequest, response: `invalid json body: json: unknown field "age"`},
+		{path: "/strict", contentType: "application/json", body: `{"name":"` + strings.Repeat("a", 32) + `"}`, statusCode: http.StatusBadRequest, response: "request body too large"},
+	}
+
+	for _, c := range cases {
+		// When
+		resp, err := http.Post(fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, c.path), c.contentType, strings.NewReader(c.body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %s, got %v %s", c.statusCode, c.body, resp.StatusCode, body)
+		}
+		if c.response != "" && string(body) != c.response {
+			t.Fatalf("Expected response %s for %s, got %s", c.response, c.body, body)
+		}
+		if strings.HasPrefix(c.response, "{") && resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("Expected json content type for %s, got %v", c.body, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+type signup struct {
+	Name  string `json:"name" validate:"required,min=2"`
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"oneof=admin user"`
+}
+
+func TestJSONHandlerBodyErrors(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.SetLimits(Limits{MaxBodySize: 16})
+	server.AddHandler("POST /greet", JSONHandler(func(req *Request, in greeting) (message, error) {
+		return message{Message: "Hello " + in.Name}, nil
+	}))
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	head := "POST /greet HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\n"
+	cases := []struct {
+		body       string
+		statusCode int
+		response   string
+	}{
+		{body: "14\r\n{\"name\":\"aaaaaaaaaa\"}\r\n0\r\n\r\n", statusCode: http.StatusRequestEntityTooLarge, response: `{"error":"request body too large"}`},
+		{body: "zz\r\n{}\r\n0\r\n\r\n", statusCode: http.StatusBadRequest, response: `{"error":"malformed chunked body: invalid chunk size \"zz\""}`},
+	}
+
+	for _, c := range cases {
+		conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%s", ServerPort))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		// When
+		_, err = conn.Write([]byte(head + c.body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		conn.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %q, got %v", c.statusCode, c.body, resp.StatusCode)
+		}
+		if string(body) != c.response {
+			t.Fatalf("Expected response %s for %q, got %s", c.response, c.body, body)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("Expected json content type for %q, got %v", c.body, resp.Header.Get("Content-Type"))
+		}
+		if !resp.Close {
+			t.Fatalf("Expected connection to be closed for %q", c.body)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("POST /signup", JSONHandler(func(req *Request, in signup) (signup, error) {
+		return in, nil
+	}))
+	server.AddHandler("POST /manual", func(req *Request, res *Response) {
+		in := signup{}
+		if err := req.DecodeJSON(&in); err != nil {
+			WriteJSONError(res, err)
+			return
+		}
+		if err := Validate(in); err != nil {
+			WriteJSONError(res, err)
+			return
+		}
+		res.JSON(http.StatusCreated, in)
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		path       string
+		body       string
+		statusCode int
+		response   string
+	}{
+		{path: "/signup", body: `{"name":"can","email":"can@example.com"}`, statusCode: http.StatusOK, response: `{"name":"can","email":"can@example.com","role":""}`},
+		{path: "/signup", body: `{"name":"c","email":"can","role":"guest"}`, statusCode: http.StatusUnprocessableEntity, response: `{"error":"validation failed","fields":[` +
+			`{"field":"name","rule":"min","message":"must have at least 2 characters"},` +
+			`{"field":"email","rule":"email","message":"must be a valid email address"},` +
+			`{"field":"role","rule":"oneof","message":"must be one of admin, user"}]}`},
+		{path: "/signup", body: `{"name":3}`, statusCode: http.StatusBadRequest},
+		{path: "/manual", body: `{"name":"can","email":"can@example.com","role":"user"}`, statusCode: http.StatusCreated},
+		{path: "/manual", body: `{}`, statusCode: http.StatusUnprocessableEntity, response: `{"error":"validation failed","fields":[` +
+			`{"field":"name","rule":"required","message":"is required"},` +
+			`{"field":"email","rule":"required","message":"is required"}]}`},
+	}
+
+	for _, c := range cases {
+		// When
+		resp, err := http.Post(fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, c.path), "application/json", strings.NewReader(c.body))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %s, got %v %s", c.statusCode, c.body, resp.StatusCode, body)
+		}
+		if c.response != "" && string(body) != c.response {
+			t.Fatalf("Expected response %s for %s, got %s", c.response, c.body, body)
+		}
+	}
+}
+
+type itemQuery struct {
+	ID     int    `path:"id" validate:"min=1"`
+	Limit  int    `query:"limit,default=20" validate:"max=100"`
+	Tenant string `header:"X-Tenant" validate:"required"`
+	Note   string `json:"note"`
+}
+
+func TestBinding(t *testing.T) {
+	// Given
+	setup()
+	server := CreateServer()
+	server.AddHandler("/items/:id", JSONHandler(func(req *Request, in itemQuery) (itemQuery, error) {
+		return in, nil
+	}))
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	cases := []struct {
+		method     string
+		path       string
+		body       string
+		statusCode int
+		response   string
+	}{
+		{method: "GET", path: "/items/5", statusCode: http.StatusOK, response: `{"ID":5,"Limit":20,"Tenant":"acme","note":""}`},
+		{method: "POST", path: "/items/5?limit=50", body: `{"note":"hi","ID":7}`, statusCode: http.StatusOK, response: `{"ID":5,"Limit":50,"Tenant":"acme","note":"hi"}`},
+		{method: "GET", path: "/items/five?limit=ten", statusCode: http.StatusBadRequest, response: `{"error":"invalid request parameters","fields":[` +
+			`{"field":"id","rule":"type","message":"must be an integer"},` +
+			`{"field":"limit","rule":"type","message":"must be an integer"}]}`},
+		{method: "GET", path: "/items/0?limit=500", statusCode: http.StatusUnprocessableEntity, response: `{"error":"validation failed","fields":[` +
+			`{"field":"id","rule":"min","message":"must be at least 1"},` +
+			`{"field":"limit","rule":"max","message":"must be at most 100"}]}`},
+	}
+
+	for _, c := range cases {
+		// When
+		req, _ := http.NewRequest(c.method, fmt.Sprintf("%s:%s%s", ServerHost, ServerPort, c.path), strings.NewReader(c.body))
+		req.Header.Set("X-Tenant", "acme")
+		if c.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %s, got %v %s", c.statusCode, c.path, resp.StatusCode, body)
+		}
+		if string(body) != c.response {
+			t.Fatalf("Expected response %s for %s, got %s", c.response, c.path, body)
+		}
+	}
+}
+
+type brokenValidation struct {
+	Name string `json:"name" validate:"min=abc"`
+}
+
+type brokenBinding struct {
+	Limit int `query:"limit,default=many"`
+}
+
+func TestJSONHandlerInvalidTags(t *testing.T) {
+	setup()
+	cases := map[string]func(){
+		"validate": func() {
+			JSONHandler(func(req *Request, in brokenValidation) (message, error) {
+				return message{}, nil
+			})
+		},
+		"query": func() {
+			JSONHandler(func(req *Request, in brokenBinding) (message, error) {
+				return message{}, nil
+			})
+		},
+	}
+
+	for name, create := range cases {
+		func() {
+			// Then
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("Expected panic for the %s tag when the handler is created", name)
+				}
+			}()
+
+			// Given
+			create()
+		}()
+	}
+}
+
+func createMultipartBody(fields map[string]string, files map[string]string) (string, *bytes.Buffer) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	for name, content := range files {
+		part, _ := writer.CreateFormFile(name, name+".txt")
+		part.Write([]byte(content))
+	}
+	writer.Close()
+	return writer.FormDataContentType(), body
+}
+
+func TestForms(t *testing.T) {
+	// Given
+	setup()
+	tempDir := t.TempDir()
+	tempFiles := -1
+	uploads := make([]*FormFile, 0)
+	server := CreateServer()
+	server.AddHandler("POST /form", func(req *Request, res *Response) {
+		form, err := req.ParseFormWith(FormOptions{MaxMemory: 8, MaxFileSize: 32, TempDir: tempDir})
+		if err != nil {
+			WriteJSONError(res, err)
+			return
+		}
+
+		// Fields first then files ordered by name so the response is stable
+		parts := []string{strings.Join(form.Values["name"], ","), req.FormValue("tag")}
+		names := make([]string, 0, len(form.Files))
+		for name := range form.Files {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			file := form.File(name)
+			uploads = append(uploads, file)
+			reader, _ := file.Open()
+			content, _ := io.ReadAll(reader)
+			reader.Close()
+			parts = append(parts, fmt.Sprintf("%s:%d:%s", file.Filename, file.Size, content))
+		}
+
+		entries, _ := os.ReadDir(tempDir)
+		tempFiles = len(entries)
+		res.Body = strings.Join(parts, "|")
+	})
+	stop, err := server.ListenAndServe(fmt.Sprintf(":%s", ServerPort))
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer close(stop)
+	time.Sleep(500 * time.Millisecond) // Delay to allow the server to start
+
+	multipartType, multipartBody := createMultipartBody(map[string]string{"name": "can", "tag": "go"}, map[string]string{"small": "tiny", "large": "larger than memory"})
+	tooLargeType, tooLargeBody := createMultipartBody(nil, map[string]string{"file": strings.Repeat("a", 33)})
+	cases := []struct {
+		contentType string
+		body        io.Reader
+		statusCode  int
+		response    string
+		tempFiles   int
+	}{
+		{contentType: "application/x-www-form-urlencoded", body: strings.NewReader("name=can&name=kurt&tag=a+b"), statusCode: http.StatusOK, response: "can,kurt|a b"},
+		{contentType: multipartType, body: multipartBody, statusCode: http.StatusOK, response: "can|go|large.txt:18:larger than memory|small.txt:4:tiny", tempFiles: 1},
+		{contentType: tooLargeType, body: tooLargeBody, statusCode: http.StatusRequestEntityTooLarge, tempFiles: -1},
+		{contentType: "multipart/form-data", body: strings.NewReader("name=can"), statusCode: http.StatusBadRequest, tempFiles: -1},
+		{contentType: "text/plain", body: strings.NewReader("name=can"), statusCode: http.StatusUnsupportedMediaType, tempFiles: -1},
+	}
+
+	for _, c := range cases {
+		// When
+		tempFiles = -1
+		resp, err := http.Post(fmt.Sprintf("%s:%s/form", ServerHost, ServerPort), c.contentType, c.body)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// Then
+		if resp.StatusCode != c.statusCode {
+			t.Fatalf("Expected status code %v for %s, got %v %s", c.statusCode, c.contentType, resp.StatusCode, body)
+		}
+		if c.response != "" && string(body) != c.response {
+			t.Fatalf("Expected response %s for %s, got %s", c.response, c.contentType, body)
+		}
+		if tempFiles != c.tempFiles {
+			t.Fatalf("Expected %v temporary files while handling %s, got %v", c.tempFiles, c.contentType, tempFiles)
+		}
+		if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+			t.Fatalf("Expected temporary files to be removed, got %v", len(entries))
+		}
+	}
+
+	// Uploads kept past the handler can not be opened
+	if len(uploads) != 2 {
+		t.Fatalf("Expected 2 uploads, got %v", len(uploads))
+	}
+	for _, file := range uploads {
+		if _, err := file.Open(); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("Expected %s to be removed, got %v", file.Filename, err)
+		}
+	}
+}
@@ -1,16 +1,80 @@
 package gohst
 
 import (
+	"net/http"
+
+	"github.com/cccaaannn/gohst/src/binding"
+	"github.com/cccaaannn/gohst/src/constant"
 	"github.com/cccaaannn/gohst/src/request"
 	"github.com/cccaaannn/gohst/src/response"
 	"github.com/cccaaannn/gohst/src/server"
+	"github.com/cccaaannn/gohst/src/url"
+	"github.com/cccaaannn/gohst/src/validation"
 )
 
 type Request = request.Request
+type Query = url.Query
 type Response = response.Response
+type Event = response.Event
+type EventStream = response.EventStream
 type HandlerFunc = server.HandlerFunc
+type Middleware = server.Middleware
+type ErrorHandler = server.ErrorHandler
+type HTTPError = server.HTTPError
+type PathOptions = server.PathOptions
+type Group = server.Group
+type Route = server.Route
+type Limits = request.Limits
+type JSONOptions = request.JSONOptions
+type FormOptions = request.FormOptions
+type Form = request.Form
+type FormFile = request.FormFile
 type Server = server.Server
+type FieldError = validation.FieldError
+type ValidationErrors = validation.Errors
+type BindingErrors = binding.Errors
+
+// ErrServerClosed is returned by Wait after Close and by Close and Shutdown on a stopped server
+var ErrServerClosed = server.ErrServerClosed
 
 func CreateServer() *Server {
 	return server.CreateServer()
 }
+
+func WrapHTTPHandler(handler http.Handler) HandlerFunc {
+	return server.WrapHTTPHandler(handler)
+}
+
+// JSONHandler creates a handler that decodes the request body into In and answers with the returned Out encoded as JSON
+func JSONHandler[In any, Out any](fn func(req *Request, in In) (Out, error)) HandlerFunc {
+	return server.JSONHandler(fn)
+}
+
+func CreateHTTPError(status int, message string) *HTTPError {
+	return server.CreateHTTPError(constant.HTTPStatusCode(status), message)
+}
+
+// Bind fills the tagged fields of the struct v points to from the path params, query, headers and cookies of the request
+func Bind(req *Request, v any) error {
+	return binding.Bind(req, v)
+}
+
+// Validate checks the fields of a struct against the rules in their "validate" tags
+func Validate(v any) error {
+	return validation.Validate(v)
+}
+
+// PrepareBinding parses the binding tags of the struct v is or points to so invalid tags panic before the first Bind
+func PrepareBinding(v any) {
+	binding.Prepare(v)
+}
+
+// PrepareValidation parses the "validate" tags of the type of v so invalid tags panic before the first Validate
+func PrepareValidation(v any) {
+	validation.Prepare(v)
+}
+
+// WriteJSONError answers with the error as JSON, validation errors are answered with 422 Unprocessable Entity
+func WriteJSONError(res *Response, err error) {
+	server.WriteJSONError(res, err)
+}
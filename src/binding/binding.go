@@ -0,0 +1,196 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/validation"
+)
+
+// Sources a field can be bound from, a field with more than one tag uses the first one in this order
+var sources = []string{"path", "query", "header", "cookie"}
+
+// Errors lists every value that could not be converted to the type of its field, it is returned as the error of Bind
+type Errors []validation.FieldError
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Field+" "+err.Message)
+	}
+	return "binding failed: " + strings.Join(messages, ", ")
+}
+
+// Where a struct field is bound from, parsed once per type
+type field struct {
+	index      int
+	source     string
+	name       string
+	layout     string
+	defaults   []string
+	hasDefault bool
+}
+
+var fieldCache sync.Map
+
+// Bind fills the tagged fields of the struct v points to from the path params, query, headers and cookies of the request
+// Tags name the value and may set a time layout and a default used when the value is missing, defaults of slices are split on commas
+//
+//	ID     int           `path:"id"`
+//	Limit  int           `query:"limit,default=20"`
+//	Since  time.Time     `query:"since,layout=2006-01-02"`
+//	Tenant *string       `header:"X-Tenant"`
+//	Wait   time.Duration `cookie:"wait,default=1s"`
+//
+// Strings, bools, numbers, durations, times, pointers and slices of them are supported, times use RFC 3339 unless a layout is set
+// Fields without a value keep theirs, values that can not be converted return Errors, unsupported fields and defaults panic, Prepare panics earlier
+func Bind(req *request.Request, v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		panic(fmt.Sprintf("Bind needs a pointer to a struct, got %T\n", v))
+	}
+
+	// Values that are not structs have no fields to bind
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var cookies map[string]string
+	errs := Errors{}
+	for _, f := range structFields(value.Type()) {
+		if f.source == "cookie" && cookies == nil {
+			cookies = req.Cookies()
+		}
+
+		values, ok := lookup(req, cookies, f)
+		if !ok {
+			if !f.hasDefault {
+				continue
+			}
+			values = f.defaults
+		}
+
+		if err := setValue(value.Field(f.index), values, f.layout); err != nil {
+			errs = append(errs, validation.FieldError{Field: f.name, Rule: "type", Message: err.Error()})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Prepare parses the tags of the struct v is or points to, so unsupported fields and defaults panic before the first Bind
+func Prepare(v any) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return
+	}
+	if t = elementType(t); t.Kind() == reflect.Struct {
+		structFields(t)
+	}
+}
+
+func lookup(req *request.Request, cookies map[string]string, f field) ([]string, bool) {
+	switch f.source {
+	case "path":
+		value, ok := req.Params[f.name]
+		return []string{value}, ok
+	case "query":
+		values, ok := req.Query[f.name]
+		return values, ok && len(values) > 0
+	case "header":
+		return req.Headers.Values(f.name), req.Headers.Has(f.name)
+	default:
+		value, ok := cookies[f.name]
+		return []string{value}, ok
+	}
+}
+
+// Exported fields of the type that have a source tag
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := make([]field, 0)
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		for _, source := range sources {
+			tag, ok := structField.Tag.Lookup(source)
+			if !ok {
+				continue
+			}
+
+			f := parseTag(tag, source, structField)
+			f.index = i
+			fields = append(fields, f)
+			break
+		}
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// Defaults may contain commas so default takes the rest of the tag
+func parseTag(tag string, source string, structField reflect.StructField) field {
+	name, options, _ := strings.Cut(tag, ",")
+	f := field{source: source, name: strings.TrimSpace(name), layout: time.RFC3339}
+	if f.name == "" {
+		f.name = structField.Name
+	}
+
+	for options != "" {
+		var option string
+		if strings.HasPrefix(options, "default=") {
+			option, options = options, ""
+		} else {
+			option, options, _ = strings.Cut(options, ",")
+		}
+
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "default":
+			f.hasDefault = true
+			f.defaults = []string{value}
+			if elementType(structField.Type).Kind() == reflect.Slice {
+				f.defaults = strings.Split(value, ",")
+			}
+		case "layout":
+			f.layout = value
+		default:
+			panic(fmt.Sprintf("Unknown %s tag option %s on field %s\n", source, key, structField.Name))
+		}
+	}
+
+	if !supported(structField.Type) {
+		panic(fmt.Sprintf("Field %s of type %s can not be bound\n", structField.Name, structField.Type))
+	}
+
+	// Defaults are written by the developer, one that does not convert is a mistake
+	if f.hasDefault {
+		if err := setValue(reflect.New(structField.Type).Elem(), f.defaults, f.layout); err != nil {
+			panic(fmt.Sprintf("Invalid default of field %s, %v\n", structField.Name, err))
+		}
+	}
+	return f
+}
+
+// Type without pointers
+func elementType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
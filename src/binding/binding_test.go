@@ -0,0 +1,167 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/header"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/url"
+	"github.com/cccaaannn/gohst/src/validation"
+)
+
+type search struct {
+	ID      int           `path:"id"`
+	Limit   int           `query:"limit,default=20"`
+	Offset  uint          `query:"offset"`
+	Ratio   float64       `query:"ratio"`
+	Debug   bool          `query:"debug"`
+	Tags    []string      `query:"tag"`
+	Ids     []int         `query:"ids,default=1,2"`
+	Since   time.Time     `query:"since,layout=2006-01-02"`
+	Until   *time.Time    `query:"until"`
+	Timeout time.Duration `header:"X-Timeout"`
+	Tenant  *string       `header:"X-Tenant"`
+	Missing *int          `header:"X-Missing"`
+	Session string        `cookie:"session"`
+	Theme   string        `cookie:"theme,default=light"`
+	Body    string        `json:"body"`
+}
+
+func createRequest(rawQuery string) *request.Request {
+	headers := header.CreateHeader()
+	headers.Set("X-Timeout", "1m30s")
+	headers.Set("X-Tenant", "acme")
+	headers.Add("Cookie", `session="abc"; lang=tr`)
+	headers.Add("Cookie", "session=other")
+
+	return &request.Request{
+		Params:  map[string]string{"id": "42"},
+		Query:   url.ParseQuery(rawQuery),
+		Headers: headers,
+	}
+}
+
+func TestBind(t *testing.T) {
+	// Given
+	req := createRequest("offset=5&ratio=0.5&debug&tag=a&tag=b&since=2024-01-02&until=2024-01-02T03:04:05Z")
+	target := search{Body: "kept"}
+
+	// When
+	err := Bind(req, &target)
+
+	// Then
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if target.ID != 42 || target.Limit != 20 || target.Offset != 5 || target.Ratio != 0.5 || !target.Debug {
+		t.Fatalf("Expected numbers and flags to be bound, got %+v", target)
+	}
+	if fmt.Sprint(target.Tags) != "[a b]" || fmt.Sprint(target.Ids) != "[1 2]" {
+		t.Fatalf("Expected slices to be bound, got %v %v", target.Tags, target.Ids)
+	}
+	if target.Since.Format(time.DateOnly) != "2024-01-02" || target.Until == nil || target.Until.Hour() != 3 {
+		t.Fatalf("Expected times to be bound, got %v %v", target.Since, target.Until)
+	}
+	if target.Timeout != 90*time.Second || target.Tenant == nil || *target.Tenant != "acme" || target.Missing != nil {
+		t.Fatalf("Expected headers to be bound, got %v %v %v", target.Timeout, target.Tenant, target.Missing)
+	}
+	if target.Session != "abc" || target.Theme != "light" || target.Body != "kept" {
+		t.Fatalf("Expected cookies to be bound and other fields kept, got %+v", target)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	// Given
+	req := createRequest("limit=ten&offset=-1&ratio=x&debug=maybe&ids=1&ids=b&since=yesterday")
+	req.Params["id"] = "99999999999999999999"
+	req.Headers.Set("X-Timeout", "soon")
+	target := search{}
+
+	// When
+	err := Bind(req, &target)
+
+	// Then
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected binding errors, got %v", err)
+	}
+	expected := "[{id type must be an integer} {limit type must be an integer} {offset type must be a non negative integer} " +
+		"{ratio type must be a number} {debug type must be a boolean} {ids type must be an integer} " +
+		"{since type must be a time in the form 2006-01-02} {X-Timeout type must be a duration like 1m30s}]"
+	if fmt.Sprint([]validation.FieldError(errs)) != expected {
+		t.Fatalf("Expected %v, got %v", expected, []validation.FieldError(errs))
+	}
+}
+
+func TestBindNonStruct(t *testing.T) {
+	// Given
+	req := createRequest("")
+	values := map[string]string{}
+
+	// When
+	err := Bind(req, &values)
+
+	// Then
+	if err != nil {
+		t.Fatalf("Expected nothing to bind, got %v", err)
+	}
+}
+
+func TestInvalidBindTag(t *testing.T) {
+	cases := []any{
+		search{},
+		&struct {
+			A int `query:"a,default=x"`
+		}{},
+		&struct {
+			A map[string]string `query:"a"`
+		}{},
+		&struct {
+			A int `query:"a,unknown=1"`
+		}{},
+	}
+
+	for _, value := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected panic for %T", value)
+				}
+			}()
+
+			Bind(createRequest(""), value)
+		}()
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	cases := []any{
+		&struct {
+			A int `query:"a,default=x"`
+		}{},
+		struct {
+			A map[string]string `header:"A"`
+		}{},
+	}
+
+	for _, value := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected panic for %T", value)
+				}
+			}()
+
+			Prepare(value)
+		}()
+	}
+
+	// Valid structs and values that are not structs do not panic
+	Prepare(search{})
+	Prepare(&search{})
+	Prepare(map[string]string{})
+	Prepare(nil)
+}
@@ -0,0 +1,119 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func supportedScalar(t reflect.Type) bool {
+	if t == durationType || t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Scalars, slices of scalars and pointers to them
+func supported(t reflect.Type) bool {
+	t = elementType(t)
+	if t.Kind() == reflect.Slice {
+		return supportedScalar(elementType(t.Elem()))
+	}
+	return supportedScalar(t)
+}
+
+// Sets the value from the values of its source, slices take every value and other types the first one
+func setValue(value reflect.Value, values []string, layout string) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		target := reflect.New(value.Type().Elem())
+		if err := setValue(target.Elem(), values, layout); err != nil {
+			return err
+		}
+		value.Set(target)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, text := range values {
+			if err := setValue(slice.Index(i), []string{text}, layout); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	}
+
+	text := ""
+	if len(values) > 0 {
+		text = values[0]
+	}
+	return setScalar(value, text, layout)
+}
+
+func setScalar(value reflect.Value, text string, layout string) error {
+	switch value.Type() {
+	case durationType:
+		duration, err := time.ParseDuration(text)
+		if err != nil {
+			return errors.New("must be a duration like 1m30s")
+		}
+		value.SetInt(int64(duration))
+		return nil
+	case timeType:
+		parsed, err := time.Parse(layout, text)
+		if err != nil {
+			return fmt.Errorf("must be a time in the form %s", layout)
+		}
+		value.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		// A query key without a value as in "?debug" is true
+		if text == "" {
+			value.SetBool(true)
+			return nil
+		}
+		flag, err := strconv.ParseBool(text)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		value.SetBool(flag)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		value.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := strconv.ParseUint(text, 10, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non negative integer")
+		}
+		value.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(text, value.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		value.SetFloat(number)
+	}
+	return nil
+}
@@ -3,8 +3,12 @@ package constant
 type ContentType string
 
 const (
-	ApplicationJson ContentType = "application/json"
-	TextHtml        ContentType = "text/html"
+	ApplicationJson           ContentType = "application/json"
+	ApplicationFormUrlencoded ContentType = "application/x-www-form-urlencoded"
+	MultipartFormData         ContentType = "multipart/form-data"
+	TextHtml                  ContentType = "text/html"
+	TextPlain                 ContentType = "text/plain"
+	TextEventStream           ContentType = "text/event-stream"
 )
 
 func (ct ContentType) String() string {
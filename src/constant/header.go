@@ -3,11 +3,19 @@ package constant
 type HttpHeader string
 
 const (
-	ContentTypeHeader   HttpHeader = "Content-Type"
-	ContentLengthHeader HttpHeader = "Content-Length"
-	DateHeader          HttpHeader = "Date"
-	ServerHeader        HttpHeader = "Server"
-	ConnectionHeader    HttpHeader = "Connection"
+	ContentTypeHeader      HttpHeader = "Content-Type"
+	ContentLengthHeader    HttpHeader = "Content-Length"
+	DateHeader             HttpHeader = "Date"
+	ServerHeader           HttpHeader = "Server"
+	ConnectionHeader       HttpHeader = "Connection"
+	TransferEncodingHeader HttpHeader = "Transfer-Encoding"
+	TrailerHeader          HttpHeader = "Trailer"
+	CacheControlHeader     HttpHeader = "Cache-Control"
+	LastEventIDHeader      HttpHeader = "Last-Event-ID"
+	UpgradeHeader          HttpHeader = "Upgrade"
+	AllowHeader            HttpHeader = "Allow"
+	LocationHeader         HttpHeader = "Location"
+	CookieHeader           HttpHeader = "Cookie"
 )
 
 func (h HttpHeader) String() string {
@@ -0,0 +1,11 @@
+package constant
+
+const (
+	GetMethod     = "GET"
+	HeadMethod    = "HEAD"
+	PostMethod    = "POST"
+	PutMethod     = "PUT"
+	PatchMethod   = "PATCH"
+	DeleteMethod  = "DELETE"
+	OptionsMethod = "OPTIONS"
+)
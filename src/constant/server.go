@@ -5,5 +5,14 @@ const (
 )
 
 const (
-	HTTPVersion = "HTTP/1.1"
+	HTTPVersion   = "HTTP/1.1"
+	HTTPVersion10 = "HTTP/1.0"
+)
+
+const (
+	DefaultMaxRequestLineLength       = 8 << 10
+	DefaultMaxHeaderBytes             = 1 << 20
+	DefaultMaxHeaderCount             = 100
+	DefaultMaxBodySize          int64 = 10 << 20
+	DefaultMaxFormMemory        int64 = 1 << 20
 )
@@ -74,6 +74,11 @@ func (c HTTPStatusCode) String() string {
 	return strconv.Itoa(int(c))
 }
 
+// AllowsBody tells whether a response with the status can have a body, informational, 204 and 304 responses can not
+func (c HTTPStatusCode) AllowsBody() bool {
+	return c >= OkStatus && c != NoContentStatus && c != NotModifiedStatus
+}
+
 func (c HTTPStatusCode) Verb() string {
 	switch c {
 	case ContinueStatus:
@@ -0,0 +1,123 @@
+package header
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+)
+
+var ErrInvalidHeader = errors.New("invalid header")
+
+// Header maps canonical header names to their values in the order they were received
+// Names are matched ignoring their casing when the methods are used, "content-type" and "Content-Type" are the same header
+type Header map[string][]string
+
+func CreateHeader() Header {
+	return make(Header)
+}
+
+// CanonicalName returns the name in the form headers are stored with, "content-type" becomes "Content-Type"
+func CanonicalName(name string) string {
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+// Get returns the first value of the header, empty if it is not set
+func (h Header) Get(name string) string {
+	values := h[CanonicalName(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Values returns every value of the header, repeated headers are not merged
+func (h Header) Values(name string) []string {
+	return h[CanonicalName(name)]
+}
+
+func (h Header) Has(name string) bool {
+	_, ok := h[CanonicalName(name)]
+	return ok
+}
+
+// Set replaces every value of the header with the given value
+func (h Header) Set(name string, value string) {
+	h[CanonicalName(name)] = []string{value}
+}
+
+// Add appends a value to the header, it is sent as a separate header line
+func (h Header) Add(name string, value string) {
+	name = CanonicalName(name)
+	h[name] = append(h[name], value)
+}
+
+func (h Header) Del(name string) {
+	delete(h, CanonicalName(name))
+}
+
+// Join returns every value of the header joined with a comma, the way list headers can be combined
+func (h Header) Join(name string) string {
+	return strings.Join(h.Values(name), ", ")
+}
+
+// HasToken reports whether the comma separated values of the header contain the token, ignoring its casing
+func (h Header) HasToken(name string, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Clone returns a copy that can be changed without changing the original
+func (h Header) Clone() Header {
+	clone := make(Header, len(h))
+	for name, values := range h {
+		clone[name] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+// ValidName reports whether the name is a token, names can not contain whitespace, separators or control characters
+func ValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte("\"(),/:;<=>?@[\\]{}", c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidValue reports whether the value can be sent, line breaks would let the value inject headers
+func ValidValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns an error for the first header that can not be sent as it is
+func (h Header) Validate() error {
+	for name, values := range h {
+		if !ValidName(name) {
+			return fmt.Errorf("%w: name %q", ErrInvalidHeader, name)
+		}
+		for _, value := range values {
+			if !ValidValue(value) {
+				return fmt.Errorf("%w: value %q of %s", ErrInvalidHeader, value, name)
+			}
+		}
+	}
+	return nil
+}
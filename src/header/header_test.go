@@ -0,0 +1,40 @@
+package header
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	// Given
+	h := CreateHeader()
+
+	// When
+	h.Add("accept", "text/html")
+	h.Add("ACCEPT", "application/json")
+	h.Set("x-request-id", "1")
+	h.Set("X-Request-Id", "2")
+	h.Set("Connection", "keep-alive, Upgrade")
+
+	// Then
+	if fmt.Sprint(h.Values("Accept")) != "[text/html application/json]" {
+		t.Fatalf("Expected both accept values, got %v", h.Values("Accept"))
+	}
+	if h.Get("accept") != "text/html" {
+		t.Fatalf("Expected first accept value, got %v", h.Get("accept"))
+	}
+	if h.Join("Accept") != "text/html, application/json" {
+		t.Fatalf("Expected joined accept values, got %v", h.Join("Accept"))
+	}
+	if fmt.Sprint(h["X-Request-Id"]) != "[2]" {
+		t.Fatalf("Expected set to replace the value, got %v", h["X-Request-Id"])
+	}
+	if !h.HasToken("connection", "upgrade") || h.HasToken("connection", "close") {
+		t.Fatalf("Expected connection tokens to be found ignoring casing, got %v", h.Values("Connection"))
+	}
+
+	h.Del("ACCEPT")
+	if h.Has("Accept") || h.Get("Accept") != "" {
+		t.Fatalf("Expected accept to be deleted, got %v", h.Values("Accept"))
+	}
+}
@@ -0,0 +1,94 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrBodyClosed = errors.New("request body is closed")
+
+// BodyReader is what a body is read from, *bufio.Reader implements it
+type BodyReader interface {
+	io.Reader
+	ReadLine() (line []byte, isPrefix bool, err error)
+}
+
+// Body is the request body, it is read from the connection only when the handler reads it
+// The server discards what the handler does not read before the next request on the connection
+type Body struct {
+	reader   io.Reader
+	err      error
+	data     []byte
+	buffered bool
+	closed   bool
+}
+
+// CreateBody creates a body that is read from the reader, nil creates an empty body
+func CreateBody(reader io.Reader) *Body {
+	if reader == nil {
+		reader = bytes.NewReader(nil)
+	}
+	return &Body{reader: reader}
+}
+
+func (body *Body) Read(p []byte) (int, error) {
+	if body.closed {
+		return 0, ErrBodyClosed
+	}
+	if body.err != nil {
+		return 0, body.err
+	}
+
+	n, err := body.reader.Read(p)
+	if err != nil && err != io.EOF {
+		body.err = err
+	}
+	return n, err
+}
+
+// Close stops the handler from reading the rest of the body
+func (body *Body) Close() error {
+	body.closed = true
+	return nil
+}
+
+// Bytes reads the rest of the body and keeps it, later calls return the same bytes and Read starts over from them
+func (body *Body) Bytes() ([]byte, error) {
+	if body.buffered {
+		return body.data, body.err
+	}
+	if body.closed {
+		return nil, ErrBodyClosed
+	}
+
+	data, err := io.ReadAll(body.reader)
+	if err != nil {
+		body.err = err
+	}
+	body.data = data
+	body.buffered = true
+	body.reader = bytes.NewReader(data)
+	return data, body.err
+}
+
+// String returns the body as text, read errors are ignored so Bytes should be used when they matter
+func (body *Body) String() string {
+	data, _ := body.Bytes()
+	return string(data)
+}
+
+// Discard reads what is left of the body so the connection can be reused, returns the first error the body ran into
+func (body *Body) Discard() error {
+	if body.err != nil {
+		return body.err
+	}
+	if body.buffered {
+		return nil
+	}
+
+	if _, err := io.Copy(io.Discard, body.reader); err != nil {
+		body.err = err
+	}
+	return body.err
+}
@@ -0,0 +1,252 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cccaaannn/gohst/src/header"
+)
+
+const (
+	maxChunkLineLength = 4096
+	maxTrailerBytes    = 8192
+)
+
+type ChunkExtension struct {
+	Name  string
+	Value string
+}
+
+// Reads a line ending with CRLF without letting a client send an endless line
+func readChunkLine(reader BodyReader, maxLength int) (string, error) {
+	var line strings.Builder
+
+	for {
+		part, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return "", fmt.Errorf("%w: unexpected end of body", ErrMalformedChunk)
+			}
+			return "", err
+		}
+
+		line.Write(part)
+		if line.Len() > maxLength {
+			return "", fmt.Errorf("%w: line too long", ErrMalformedChunk)
+		}
+		if !isPrefix {
+			return line.String(), nil
+		}
+	}
+}
+
+// Chunk size line is in the form of "1a;name=value;other", quoted extension values are unquoted
+func parseChunkSizeLine(line string) (int64, []ChunkExtension, error) {
+	sizeText, extensionText := line, ""
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		sizeText, extensionText = line[:i], line[i:]
+	}
+
+	sizeText = strings.TrimSpace(sizeText)
+	if sizeText == "" || len(sizeText) > 16 {
+		return 0, nil, fmt.Errorf("%w: invalid chunk size %q", ErrMalformedChunk, sizeText)
+	}
+	size, err := strconv.ParseInt(sizeText, 16, 64)
+	if err != nil || size < 0 {
+		return 0, nil, fmt.Errorf("%w: invalid chunk size %q", ErrMalformedChunk, sizeText)
+	}
+
+	extensions, err := parseChunkExtensions(extensionText)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, extensions, nil
+}
+
+// Extensions are ";name" or ";name=value" with optional whitespace around the separators, RFC 9112 section 7.1.1
+// Names are tokens, values are tokens or quoted strings where a backslash escapes the next character
+func parseChunkExtensions(text string) ([]ChunkExtension, error) {
+	extensions := make([]ChunkExtension, 0)
+	for {
+		text = strings.TrimLeft(text, " \t")
+		if text == "" {
+			return extensions, nil
+		}
+		if text[0] != ';' {
+			return nil, fmt.Errorf("%w: invalid chunk extension %q", ErrMalformedChunk, text)
+		}
+
+		var name string
+		name, text = cutToken(strings.TrimLeft(text[1:], " \t"))
+		if name == "" {
+			return nil, fmt.Errorf("%w: empty chunk extension", ErrMalformedChunk)
+		}
+
+		value := ""
+		if rest := strings.TrimLeft(text, " \t"); strings.HasPrefix(rest, "=") {
+			rest = strings.TrimLeft(rest[1:], " \t")
+			var err error
+			if strings.HasPrefix(rest, "\"") {
+				value, text, err = cutQuotedString(rest)
+			} else if value, text = cutToken(rest); value == "" {
+				err = fmt.Errorf("%w: empty chunk extension value of %q", ErrMalformedChunk, name)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		extensions = append(extensions, ChunkExtension{Name: name, Value: value})
+	}
+}
+
+// Splits the leading token off the text, the token is empty if the text does not start with one
+func cutToken(text string) (string, string) {
+	end := strings.IndexAny(text, " \t;=\"")
+	if end < 0 {
+		end = len(text)
+	}
+	if !header.ValidName(text[:end]) {
+		return "", text
+	}
+	return text[:end], text[end:]
+}
+
+// Splits the leading quoted string off the text and unquotes it, text starts with the opening quote
+func cutQuotedString(text string) (string, string, error) {
+	var value strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"':
+			return value.String(), text[i+1:], nil
+		case c == '\\' && i+1 < len(text) && quotable(text[i+1]):
+			i++
+			value.WriteByte(text[i])
+		case c != '\\' && quotable(c):
+			value.WriteByte(c)
+		default:
+			return "", "", fmt.Errorf("%w: invalid quoted string %q", ErrMalformedChunk, text)
+		}
+	}
+	return "", "", fmt.Errorf("%w: unterminated quoted string %q", ErrMalformedChunk, text)
+}
+
+// Characters a quoted string may contain, tab, space, visible characters and obs-text
+func quotable(c byte) bool {
+	return c == '\t' || (c >= ' ' && c != 0x7f)
+}
+
+func readTrailers(reader BodyReader) (header.Header, error) {
+	var trailers strings.Builder
+
+	for {
+		line, err := readChunkLine(reader, maxChunkLineLength)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+
+		trailers.WriteString(line)
+		trailers.WriteString("\r\n")
+		if trailers.Len() > maxTrailerBytes {
+			return nil, fmt.Errorf("%w: trailers too large", ErrMalformedChunk)
+		}
+	}
+
+	trailerMap, err := parseHeaders(trailers.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedChunk, err)
+	}
+	return trailerMap, nil
+}
+
+// Decodes a body sent with "Transfer-Encoding: chunked" while it is read, decoded size can not exceed maxBodySize
+// Extensions and trailers are set on the request as they are read, trailers are only known after the whole body is read
+type chunkedReader struct {
+	reader      BodyReader
+	req         *Request
+	maxBodySize int64
+	read        int64
+	remaining   int64
+	dataEnded   bool
+	done        bool
+}
+
+// Reads the next chunk size line, the last chunk is followed by the trailers
+func (chunked *chunkedReader) nextChunk() error {
+	// Every chunk data ends with CRLF
+	if chunked.dataEnded {
+		end, err := readChunkLine(chunked.reader, maxChunkLineLength)
+		if err != nil {
+			return err
+		}
+		if end != "" {
+			return fmt.Errorf("%w: missing CRLF after chunk data", ErrMalformedChunk)
+		}
+		chunked.dataEnded = false
+	}
+
+	line, err := readChunkLine(chunked.reader, maxChunkLineLength)
+	if err != nil {
+		return err
+	}
+
+	size, extensions, err := parseChunkSizeLine(line)
+	if err != nil {
+		return err
+	}
+	chunked.req.ChunkExtensions = append(chunked.req.ChunkExtensions, extensions...)
+
+	// Last chunk, trailers follow
+	if size == 0 {
+		trailers, err := readTrailers(chunked.reader)
+		if err != nil {
+			return err
+		}
+		chunked.req.Trailers = trailers
+		chunked.done = true
+		return nil
+	}
+
+	// Compared with what is left so a huge size can not overflow, bodies without a limit still can not pass what int64 holds
+	limit := int64(math.MaxInt64)
+	if chunked.maxBodySize > 0 {
+		limit = chunked.maxBodySize
+	}
+	if size > limit-chunked.read {
+		return ErrBodyTooLarge
+	}
+	chunked.remaining = size
+	return nil
+}
+
+func (chunked *chunkedReader) Read(p []byte) (int, error) {
+	if !chunked.done && chunked.remaining == 0 {
+		if err := chunked.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if chunked.done {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > chunked.remaining {
+		p = p[:chunked.remaining]
+	}
+	n, err := chunked.reader.Read(p)
+	chunked.read += int64(n)
+	chunked.remaining -= int64(n)
+	if chunked.remaining == 0 {
+		chunked.dataEnded = true
+	}
+
+	if err == io.EOF {
+		return n, fmt.Errorf("%w: %w", ErrMalformedChunk, io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
@@ -0,0 +1,37 @@
+package request
+
+import (
+	"strings"
+
+	"github.com/cccaaannn/gohst/src/constant"
+)
+
+// Cookies returns the cookies the client sent, pairs are in the form "name=value" separated by semicolons
+// The first value wins when a name is repeated, browsers send the most specific cookie first
+func (req *Request) Cookies() map[string]string {
+	cookies := make(map[string]string)
+	for _, line := range req.Headers.Values(constant.CookieHeader.String()) {
+		for _, pair := range strings.Split(line, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" {
+				continue
+			}
+			if _, exists := cookies[name]; exists {
+				continue
+			}
+
+			// Values may be quoted, the quotes are not part of the value
+			if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+				value = value[1 : len(value)-1]
+			}
+			cookies[name] = value
+		}
+	}
+	return cookies
+}
+
+// Cookie returns the value of the named cookie and whether the client sent it
+func (req *Request) Cookie(name string) (string, bool) {
+	value, ok := req.Cookies()[name]
+	return value, ok
+}
@@ -0,0 +1,19 @@
+package request
+
+import "errors"
+
+// Errors returned while reading a request, they are wrapped with details so they should be compared with errors.Is
+var (
+	ErrBadRequestLine              = errors.New("malformed request line")
+	ErrUnsupportedVersion          = errors.New("unsupported http version")
+	ErrBadHeader                   = errors.New("malformed header")
+	ErrInvalidContentLength        = errors.New("invalid content length")
+	ErrUnsupportedTransferEncoding = errors.New("unsupported transfer encoding")
+	ErrMalformedChunk              = errors.New("malformed chunked body")
+	ErrRequestLineTooLong          = errors.New("request line too long")
+	ErrHeaderTooLarge              = errors.New("request headers too large")
+	ErrBodyTooLarge                = errors.New("request body too large")
+	ErrUnsupportedMediaType        = errors.New("unsupported media type")
+	ErrInvalidJSON                 = errors.New("invalid json body")
+	ErrMalformedForm               = errors.New("malformed form body")
+)
@@ -0,0 +1,307 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/header"
+	"github.com/cccaaannn/gohst/src/url"
+)
+
+// FormOptions bound how a form is read, zero size limits mean no limit besides the request limits
+type FormOptions struct {
+	// Uploaded files are kept in memory until together they pass this size, the rest is written to temporary files, zero writes every file
+	MaxMemory int64
+	// Largest uploaded file, larger files return ErrBodyTooLarge
+	MaxFileSize int64
+	// Largest total size of the fields and files, larger forms return ErrBodyTooLarge
+	MaxTotalSize int64
+	// Directory of the temporary files, the default temporary directory is used if it is empty
+	TempDir string
+}
+
+func DefaultFormOptions() FormOptions {
+	return FormOptions{
+		MaxMemory: constant.DefaultMaxFormMemory,
+	}
+}
+
+// Form is a parsed form body, fields keep every value like a query and files are grouped by their field name
+type Form struct {
+	Values url.Query
+	Files  map[string][]*FormFile
+}
+
+// FormFile is an uploaded file, its content is kept in memory or in a temporary file removed after the handler returns
+type FormFile struct {
+	Filename string
+	Headers  header.Header
+	Size     int64
+
+	data    []byte
+	path    string
+	removed bool
+}
+
+// Open returns a reader of the file content, it should be closed after reading
+// Files can not be opened after the handler returns, os.ErrNotExist is returned then
+func (file *FormFile) Open() (io.ReadCloser, error) {
+	if file.removed {
+		return nil, fmt.Errorf("%w: upload %q was removed after the handler returned", os.ErrNotExist, file.Filename)
+	}
+	if file.path == "" {
+		return io.NopCloser(bytes.NewReader(file.data)), nil
+	}
+	return os.Open(file.path)
+}
+
+// Value returns the first value of the field, an empty string if it is missing
+func (form *Form) Value(name string) string {
+	return form.Values.Get(name)
+}
+
+// File returns the first file uploaded in the field, nil if there is none
+func (form *Form) File(name string) *FormFile {
+	files := form.Files[name]
+	if len(files) == 0 {
+		return nil
+	}
+	return files[0]
+}
+
+// Removes the temporary files of the uploads, files kept in memory are released too so none of them can be opened again
+func (form *Form) removeFiles() error {
+	var errs []error
+	for _, files := range form.Files {
+		for _, file := range files {
+			if file.path != "" {
+				if err := os.Remove(file.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, err)
+				}
+			}
+			file.data = nil
+			file.path = ""
+			file.removed = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseForm reads an "application/x-www-form-urlencoded" or "multipart/form-data" body with the default options
+func (req *Request) ParseForm() (*Form, error) {
+	return req.ParseFormWith(DefaultFormOptions())
+}
+
+// ParseFormWith reads an "application/x-www-form-urlencoded" or "multipart/form-data" body, the form is kept so later calls return it
+// Bodies of other types return ErrUnsupportedMediaType and bodies that can not be parsed ErrMalformedForm
+func (req *Request) ParseFormWith(options FormOptions) (*Form, error) {
+	if req.form != nil {
+		return req.form, nil
+	}
+
+	contentType := req.Headers.Get(constant.ContentTypeHeader.String())
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+
+	var form *Form
+	switch mediaType {
+	case constant.ApplicationFormUrlencoded.String():
+		form, err = req.parseUrlencodedForm(options)
+	case constant.MultipartFormData.String():
+		if params["boundary"] == "" {
+			return nil, fmt.Errorf("%w: missing boundary", ErrMalformedForm)
+		}
+		form, err = req.parseMultipartForm(params["boundary"], options)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req.form = form
+	return form, nil
+}
+
+// FormValue returns the first value of the form field, an empty string if it is missing or the form can not be parsed
+func (req *Request) FormValue(name string) string {
+	form, err := req.ParseForm()
+	if err != nil {
+		return ""
+	}
+	return form.Value(name)
+}
+
+// RemoveFiles removes the temporary files of the uploaded files, the server calls it after the handler returns
+func (req *Request) RemoveFiles() error {
+	if req.form == nil {
+		return nil
+	}
+	return req.form.removeFiles()
+}
+
+func (req *Request) parseUrlencodedForm(options FormOptions) (*Form, error) {
+	var reader io.Reader = req.Body
+	if options.MaxTotalSize > 0 {
+		reader = io.LimitReader(req.Body, options.MaxTotalSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if options.MaxTotalSize > 0 && int64(len(data)) > options.MaxTotalSize {
+		return nil, ErrBodyTooLarge
+	}
+
+	return &Form{Values: url.ParseQuery(string(data)), Files: make(map[string][]*FormFile)}, nil
+}
+
+func (req *Request) parseMultipartForm(boundary string, options FormOptions) (*Form, error) {
+	form := &Form{Values: url.Query{}, Files: make(map[string][]*FormFile)}
+	reader := multipart.NewReader(req.Body, boundary)
+	parser := &formParser{body: req.Body, options: options, memory: options.MaxMemory}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			form.removeFiles()
+			return nil, parser.error(err)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			_, err = parser.copy(&value, part, -1)
+			if err == nil {
+				form.Values[name] = append(form.Values[name], value.String())
+			}
+		} else {
+			var file *FormFile
+			file, err = parser.readFile(part)
+			if err == nil {
+				form.Files[name] = append(form.Files[name], file)
+			}
+		}
+		part.Close()
+
+		if err != nil {
+			form.removeFiles()
+			return nil, err
+		}
+	}
+}
+
+// Keeps the sizes of a multipart form while its parts are read
+type formParser struct {
+	body    *Body
+	options FormOptions
+	total   int64
+	// Memory left for files before they are written to temporary files
+	memory int64
+}
+
+// Copies the part within the total size and the max size, negative max means no limit
+func (parser *formParser) copy(writer io.Writer, part io.Reader, max int64) (int64, error) {
+	limit := int64(-1)
+	if parser.options.MaxTotalSize > 0 {
+		limit = parser.options.MaxTotalSize - parser.total
+	}
+	if max >= 0 && (limit < 0 || max < limit) {
+		limit = max
+	}
+
+	reader := part
+	if limit >= 0 {
+		reader = io.LimitReader(part, limit+1)
+	}
+	n, err := io.Copy(writer, reader)
+	parser.total += n
+	if err != nil {
+		return n, parser.error(err)
+	}
+	if limit >= 0 && n > limit {
+		return n, ErrBodyTooLarge
+	}
+	return n, nil
+}
+
+// Reads the file into memory while there is memory left, the rest of it goes to a temporary file
+func (parser *formParser) readFile(part *multipart.Part) (*FormFile, error) {
+	file := &FormFile{
+		Filename: part.FileName(),
+		Headers:  header.CreateHeader(),
+	}
+	for name, values := range part.Header {
+		for _, value := range values {
+			file.Headers.Add(name, value)
+		}
+	}
+
+	// One byte more than the memory left tells whether the file fits
+	var buffer bytes.Buffer
+	memory := parser.memory
+	if memory < 0 {
+		memory = 0
+	}
+	maxFileSize := int64(-1)
+	if parser.options.MaxFileSize > 0 {
+		maxFileSize = parser.options.MaxFileSize
+	}
+	n, err := parser.copy(&buffer, io.LimitReader(part, memory+1), maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if n <= memory {
+		parser.memory -= n
+		file.data = buffer.Bytes()
+		file.Size = n
+		return file, nil
+	}
+
+	temp, err := os.CreateTemp(parser.options.TempDir, "gohst-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	file.path = temp.Name()
+	defer temp.Close()
+
+	if _, err := temp.Write(buffer.Bytes()); err != nil {
+		os.Remove(file.path)
+		return nil, err
+	}
+
+	if maxFileSize >= 0 {
+		maxFileSize -= n
+	}
+	rest, err := parser.copy(temp, part, maxFileSize)
+	if err != nil {
+		os.Remove(file.path)
+		return nil, err
+	}
+	file.Size = n + rest
+	return file, nil
+}
+
+// Errors of reading the body are kept as they are, other errors are from a form the client sent malformed
+func (parser *formParser) error(err error) error {
+	if parser.body.err != nil {
+		return parser.body.err
+	}
+	return fmt.Errorf("%w: %v", ErrMalformedForm, err)
+}
@@ -0,0 +1,81 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/cccaaannn/gohst/src/constant"
+)
+
+// JSONOptions change how DecodeJSONWith reads the body, the zero value accepts unknown fields and only the request limits bound the size
+type JSONOptions struct {
+	// Fields that are not in the target struct are rejected instead of ignored
+	DisallowUnknownFields bool
+	// Largest body accepted, larger bodies return ErrBodyTooLarge, zero means no limit
+	MaxSize int64
+}
+
+// HasBody reports whether the client sent a body, the body of a request without one reads as empty
+func (req *Request) HasBody() bool {
+	if req.Headers.Has(constant.TransferEncodingHeader.String()) {
+		return true
+	}
+	contentLength := req.Headers.Get(constant.ContentLengthHeader.String())
+	return contentLength != "" && strings.Trim(contentLength, "0") != ""
+}
+
+// DecodeJSON reads the body as a single JSON value into v, unknown fields are ignored
+func (req *Request) DecodeJSON(v any) error {
+	return req.DecodeJSONWith(v, JSONOptions{})
+}
+
+// DecodeJSONWith reads the body as a single JSON value into v
+// Bodies that are not declared as JSON return ErrUnsupportedMediaType and bodies that can not be decoded ErrInvalidJSON
+func (req *Request) DecodeJSONWith(v any, options JSONOptions) error {
+	contentType := req.Headers.Get(constant.ContentTypeHeader.String())
+	if !isJSONMediaType(contentType) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+
+	var reader io.Reader = req.Body
+	if options.MaxSize > 0 {
+		reader = io.LimitReader(req.Body, options.MaxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if options.MaxSize > 0 && int64(len(data)) > options.MaxSize {
+		return ErrBodyTooLarge
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if options.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("%w: empty body", ErrInvalidJSON)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+
+	// Only one value is expected, anything after it is not part of the request
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("%w: unexpected data after the value", ErrInvalidJSON)
+	}
+	return nil
+}
+
+// Accepts "application/json" and structured types like "application/problem+json", parameters like charset are ignored
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == constant.ApplicationJson.String() || (strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,56 @@
+package request
+
+import "github.com/cccaaannn/gohst/src/constant"
+
+// Limits bound the size of a request, zero fields mean no limit
+type Limits struct {
+	// Longest request line, longer ones are answered with 414 URI Too Long
+	MaxRequestLineLength int
+	// Total size of the header lines, larger heads are answered with 431 Request Header Fields Too Large
+	MaxHeaderBytes int
+	// Number of header lines, more headers are answered with 431 Request Header Fields Too Large
+	MaxHeaderCount int
+	// Size of the body after decoding, larger bodies are answered with 413 Payload Too Large
+	MaxBodySize int64
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxRequestLineLength: constant.DefaultMaxRequestLineLength,
+		MaxHeaderBytes:       constant.DefaultMaxHeaderBytes,
+		MaxHeaderCount:       constant.DefaultMaxHeaderCount,
+		MaxBodySize:          constant.DefaultMaxBodySize,
+	}
+}
+
+// Merge returns the limits with the non zero fields of override replacing their values
+func (limits Limits) Merge(override Limits) Limits {
+	if override.MaxRequestLineLength != 0 {
+		limits.MaxRequestLineLength = override.MaxRequestLineLength
+	}
+	if override.MaxHeaderBytes != 0 {
+		limits.MaxHeaderBytes = override.MaxHeaderBytes
+	}
+	if override.MaxHeaderCount != 0 {
+		limits.MaxHeaderCount = override.MaxHeaderCount
+	}
+	if override.MaxBodySize != 0 {
+		limits.MaxBodySize = override.MaxBodySize
+	}
+	return limits
+}
+
+// CheckHead checks an already read request head against the limits
+// Heads are read with the server limits, this lets a route apply stricter ones before its body is read
+func (limits Limits) CheckHead(req *Request) error {
+	if limits.MaxRequestLineLength > 0 && req.requestLineLength > limits.MaxRequestLineLength {
+		return ErrRequestLineTooLong
+	}
+	if limits.MaxHeaderBytes > 0 && req.headerBytes > limits.MaxHeaderBytes {
+		return ErrHeaderTooLarge
+	}
+	if limits.MaxHeaderCount > 0 && req.headerCount > limits.MaxHeaderCount {
+		return ErrHeaderTooLarge
+	}
+	return nil
+}
@@ -2,36 +2,105 @@ package request
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"net"
 	"strconv"
 	"strings"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/header"
+	"github.com/cccaaannn/gohst/src/url"
 )
 
 type Request struct {
-	Method   string
-	Path     string
-	Protocol string
-	Body     string
-	Query    map[string]string
-	Params   map[string]string
-	Headers  map[string]string
-	Context  map[string]any
+	Method          string
+	Path            string
+	RawPath         string
+	Protocol        string
+	RemoteAddr      string
+	Body            *Body
+	Query           url.Query
+	Params          map[string]string
+	Headers         header.Header
+	Trailers        header.Header
+	ChunkExtensions []ChunkExtension
+	Context         map[string]any
+
+	requestLineLength int
+	headerBytes       int
+	headerCount       int
+	form              *Form
 }
 
-func readUntilBody(reader *bufio.Reader) (string, error) {
+// Request head as it was read, sizes are kept so route limits can be checked after routing
+type head struct {
+	text              string
+	requestLineLength int
+	headerBytes       int
+	headerCount       int
+}
+
+// Reads a line with its line ending, lines longer than maxLength without the line ending return tooLong
+func readLimitedLine(reader *bufio.Reader, maxLength int, tooLong error) (string, error) {
+	var line strings.Builder
+
+	for {
+		part, err := reader.ReadSlice('\n')
+		line.Write(part)
+
+		// Stop reading early, the line ending is checked once the line is complete
+		if maxLength > 0 && line.Len() > maxLength+2 {
+			return "", tooLong
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil && maxLength > 0 && len(strings.TrimRight(line.String(), "\r\n")) > maxLength {
+			return "", tooLong
+		}
+		return line.String(), err
+	}
+}
+
+func readUntilBody(reader *bufio.Reader, limits Limits) (head, error) {
 	var headers strings.Builder
+	result := head{}
 
 	for {
-		header, err := reader.ReadString('\n')
+		maxLength, tooLong := limits.MaxHeaderBytes, ErrHeaderTooLarge
+		if headers.Len() == 0 {
+			maxLength, tooLong = limits.MaxRequestLineLength, ErrRequestLineTooLong
+		}
+
+		header, err := readLimitedLine(reader, maxLength, tooLong)
 		if err != nil {
 			if err == io.EOF {
+				// Connection closed before a request arrived, report it so keep-alive loops can stop quietly
+				if headers.Len() == 0 && header == "" {
+					return head{}, io.EOF
+				}
 				break
 			}
+			if errors.Is(err, tooLong) {
+				return head{}, err
+			}
 
 			fmt.Println("Error reading:", err.Error())
-			return "", err
+			return head{}, err
+		}
+
+		if headers.Len() == 0 {
+			result.requestLineLength = len(strings.TrimRight(header, "\r\n"))
+		} else if header != "\r\n" {
+			result.headerBytes += len(header)
+			result.headerCount++
+			if limits.MaxHeaderBytes > 0 && result.headerBytes > limits.MaxHeaderBytes {
+				return head{}, ErrHeaderTooLarge
+			}
+			if limits.MaxHeaderCount > 0 && result.headerCount > limits.MaxHeaderCount {
+				return head{}, ErrHeaderTooLarge
+			}
 		}
 
 		headers.WriteString(header)
@@ -41,47 +110,95 @@ func readUntilBody(reader *bufio.Reader) (string, error) {
 		}
 	}
 
-	return headers.String(), nil
+	result.text = headers.String()
+	return result, nil
 }
 
-func readBody(reader *bufio.Reader, headers string) (string, error) {
-	var bodyBuffer strings.Builder
-	contentLengthHeaderStr := "Content-Length: "
+// Content-Length is a decimal number, a list of identical values is accepted as a single value
+func parseContentLength(value string) (int64, error) {
+	var contentLength int64 = -1
 
-	if strings.Contains(headers, contentLengthHeaderStr) {
-		// Extract the content length
-		start := strings.Index(headers, contentLengthHeaderStr) + len(contentLengthHeaderStr)
-		end := strings.Index(headers[start:], "\r\n")
-		contentLength, err := strconv.Atoi(headers[start : start+end])
-		if err != nil {
-			fmt.Println("Error parsing Content-Length:", err.Error())
-			return "", err
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidContentLength, value)
 		}
 
-		// Read the body based on the content length
-		body := make([]byte, contentLength)
-		_, err = io.ReadFull(reader, body)
-		if err != nil {
-			fmt.Println("Error reading body:", err.Error())
-			return "", err
+		length, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || (contentLength >= 0 && length != contentLength) {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidContentLength, value)
 		}
-		bodyBuffer.Write(body)
+		contentLength = length
 	}
 
-	return bodyBuffer.String(), nil
+	return contentLength, nil
+}
+
+// Reads exactly the declared length, a body ending early is an error
+type contentLengthReader struct {
+	reader    io.Reader
+	remaining int64
 }
 
+func (body *contentLengthReader) Read(p []byte) (int, error) {
+	if body.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > body.remaining {
+		p = p[:body.remaining]
+	}
+	n, err := body.reader.Read(p)
+	body.remaining -= int64(n)
+	if err == io.EOF && body.remaining > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Request line is in the form of "GET /path HTTP/1.1" with single spaces between the parts
 func parseRequestLine(requestLine string) (string, string, string, error) {
-	requestParts := strings.Fields(requestLine)
+	requestParts := strings.Split(strings.TrimSuffix(requestLine, "\r\n"), " ")
 
-	if len(requestParts) < 3 {
-		return "", "", "", fmt.Errorf("invalid request line")
+	// Methods are tokens like header names
+	if len(requestParts) != 3 || !header.ValidName(requestParts[0]) || requestParts[1] == "" {
+		return "", "", "", fmt.Errorf("%w: %q", ErrBadRequestLine, requestLine)
+	}
+	for _, c := range []byte(requestParts[1]) {
+		if c <= ' ' || c == 0x7f {
+			return "", "", "", fmt.Errorf("%w: %q", ErrBadRequestLine, requestLine)
+		}
 	}
-	return requestParts[0], requestParts[1], requestParts[2], nil
+
+	protocol := requestParts[2]
+	if _, _, ok := parseHTTPVersion(protocol); !ok {
+		return "", "", "", fmt.Errorf("%w: %q", ErrBadRequestLine, requestLine)
+	}
+	if protocol != constant.HTTPVersion && protocol != constant.HTTPVersion10 {
+		return "", "", "", fmt.Errorf("%w: %q", ErrUnsupportedVersion, protocol)
+	}
+
+	return requestParts[0], requestParts[1], protocol, nil
 }
 
-func parseHeaders(headers string) map[string]string {
-	headerMap := make(map[string]string)
+// Version is in the form of "HTTP/1.1" with single digit major and minor versions
+func parseHTTPVersion(protocol string) (int, int, bool) {
+	if len(protocol) != len("HTTP/1.1") || !strings.HasPrefix(protocol, "HTTP/") || protocol[6] != '.' {
+		return 0, 0, false
+	}
+	major, minor := protocol[5], protocol[7]
+	if major < '0' || major > '9' || minor < '0' || minor > '9' {
+		return 0, 0, false
+	}
+	return int(major - '0'), int(minor - '0'), true
+}
+
+// Header lines are in the form of "Name: value", whitespace around the value is ignored and repeated headers keep every value
+func parseHeaders(headers string) (header.Header, error) {
+	headerMap := header.CreateHeader()
 
 	headerLines := strings.Split(headers, "\r\n")
 	for _, headerLine := range headerLines {
@@ -89,44 +206,122 @@ func parseHeaders(headers string) map[string]string {
 			continue
 		}
 
-		headerParts := strings.Split(headerLine, ": ")
-		if len(headerParts) != 2 {
-			continue
+		name, value, ok := strings.Cut(headerLine, ":")
+		if !ok || !header.ValidName(name) {
+			return nil, fmt.Errorf("%w: %q", ErrBadHeader, headerLine)
 		}
-		headerMap[headerParts[0]] = headerParts[1]
+		value = strings.Trim(value, " \t")
+		if !header.ValidValue(value) {
+			return nil, fmt.Errorf("%w: %q", ErrBadHeader, headerLine)
+		}
+		headerMap.Add(name, value)
 	}
 
-	return headerMap
+	return headerMap, nil
 }
 
-func ParseRequest(conn net.Conn) (*Request, error) {
-	reader := bufio.NewReader(conn)
+// LastEventID returns the id of the last event an event stream client received before reconnecting
+func (req *Request) LastEventID() string {
+	return req.Headers.Get(constant.LastEventIDHeader.String())
+}
 
-	headers, err := readUntilBody(reader)
+func isChunked(headers header.Header) (bool, error) {
+	if !headers.Has(constant.TransferEncodingHeader.String()) {
+		return false, nil
+	}
+	transferEncoding := headers.Join(constant.TransferEncodingHeader.String())
+
+	// Only chunked is decoded, other codings would reach the handler still encoded
+	if !strings.EqualFold(strings.TrimSpace(transferEncoding), "chunked") {
+		return false, fmt.Errorf("%w: %q", ErrUnsupportedTransferEncoding, transferEncoding)
+	}
+	return true, nil
+}
+
+// ParseRequestHead reads the request line and headers within the limits, the body is left on the reader for ReadBody
+func ParseRequestHead(reader *bufio.Reader, limits Limits) (*Request, error) {
+	head, err := readUntilBody(reader, limits)
 	if err != nil {
 		return nil, err
 	}
+	requestLine, headers, _ := strings.Cut(head.text, "\r\n")
 
-	method, path, protocol, err := parseRequestLine(headers)
+	method, path, protocol, err := parseRequestLine(requestLine)
 	if err != nil {
 		return nil, err
 	}
 	fmt.Printf("%s %s %s\n", method, path, protocol)
 
-	headerMap := parseHeaders(headers)
-	body, err := readBody(reader, headers)
+	headerMap, err := parseHeaders(headers)
 	if err != nil {
 		return nil, err
 	}
 
 	req := &Request{
-		Method:   method,
-		Path:     path,
-		Protocol: protocol,
-		Body:     body,
-		Headers:  headerMap,
-		Context:  make(map[string]any),
+		Method:            method,
+		Body:              CreateBody(nil),
+		Path:              path,
+		RawPath:           path,
+		Protocol:          protocol,
+		Headers:           headerMap,
+		Trailers:          header.CreateHeader(),
+		ChunkExtensions:   make([]ChunkExtension, 0),
+		Context:           make(map[string]any),
+		requestLineLength: head.requestLineLength,
+		headerBytes:       head.headerBytes,
+		headerCount:       head.headerCount,
 	}
 
 	return req, nil
 }
+
+// AttachBody sets the body that follows the head on the request, it is read from the reader only when the handler reads it
+// Declared lengths larger than maxBodySize are rejected here, chunked bodies when they grow past it, zero means no limit
+func (req *Request) AttachBody(reader BodyReader, maxBodySize int64) error {
+	chunked, err := isChunked(req.Headers)
+	if err != nil {
+		return err
+	}
+
+	// Transfer-Encoding overrides Content-Length
+	if chunked {
+		req.Body = CreateBody(&chunkedReader{reader: reader, req: req, maxBodySize: maxBodySize})
+		return nil
+	}
+
+	if !req.Headers.Has(constant.ContentLengthHeader.String()) {
+		req.Body = CreateBody(nil)
+		return nil
+	}
+
+	// Repeated Content-Length headers are only valid if they are the same
+	contentLength, err := parseContentLength(req.Headers.Join(constant.ContentLengthHeader.String()))
+	if err != nil {
+		return err
+	}
+
+	// Checked before reading, the length is sent by the client
+	if maxBodySize > 0 && contentLength > maxBodySize {
+		return ErrBodyTooLarge
+	}
+
+	req.Body = CreateBody(&contentLengthReader{reader: reader, remaining: contentLength})
+	return nil
+}
+
+// ParseRequest reads a single request from the reader, the same reader should be reused for following requests on a connection
+// Requests exceeding the limits are rejected, zero fields mean no limit
+func ParseRequest(reader *bufio.Reader, limits Limits) (*Request, error) {
+	req, err := ParseRequestHead(reader, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := req.AttachBody(reader, limits.MaxBodySize); err != nil {
+		return nil, err
+	}
+	if _, err := req.Body.Bytes(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,150 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/constant"
+)
+
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	// Tells the client how long to wait before reconnecting, zero is not sent
+	Retry time.Duration
+}
+
+// EventStream writes server-sent events to the client, it is safe to use from multiple goroutines
+type EventStream struct {
+	res       *Response
+	mu        sync.Mutex
+	done      <-chan struct{}
+	heartbeat chan struct{}
+	closed    bool
+}
+
+// EventStream sends the "text/event-stream" headers and returns a stream to send events with
+// Handler should keep running while it sends events, the stream ends when the handler returns
+func (res *Response) EventStream() (*EventStream, error) {
+	if res.headersSent {
+		return nil, errors.New("headers are already sent")
+	}
+
+	res.Headers.Set(constant.ContentTypeHeader.String(), constant.TextEventStream.String())
+	res.Headers.Set(constant.CacheControlHeader.String(), "no-cache")
+
+	stream := &EventStream{
+		res:  res,
+		done: res.CloseNotify(),
+	}
+	res.onClose = append(res.onClose, stream.Close)
+
+	if err := res.Flush(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// Done returns a channel that is closed when the client disconnects
+func (stream *EventStream) Done() <-chan struct{} {
+	return stream.done
+}
+
+func (stream *EventStream) write(text string) error {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.closed {
+		return errors.New("event stream is closed")
+	}
+
+	if _, err := stream.res.WriteString(text); err != nil {
+		return err
+	}
+	return stream.res.Flush()
+}
+
+// Send writes a single event, multi line data is sent as multiple data fields
+func (stream *EventStream) Send(event Event) error {
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return errors.New("event id and name can not contain new lines")
+	}
+
+	var text strings.Builder
+	if event.ID != "" {
+		fmt.Fprintf(&text, "id: %s\n", event.ID)
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&text, "event: %s\n", event.Event)
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&text, "retry: %d\n", event.Retry.Milliseconds())
+	}
+
+	data := strings.ReplaceAll(event.Data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&text, "data: %s\n", line)
+	}
+	text.WriteString("\n")
+
+	return stream.write(text.String())
+}
+
+// Comment writes a comment line, clients ignore it but it keeps proxies from closing the connection
+func (stream *EventStream) Comment(comment string) error {
+	var text strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(comment, "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&text, ": %s\n", line)
+	}
+	text.WriteString("\n")
+
+	return stream.write(text.String())
+}
+
+// Heartbeat sends a comment every interval until the stream is closed or the client disconnects, intervals that are not positive send none
+func (stream *EventStream) Heartbeat(interval time.Duration) {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if interval <= 0 || stream.heartbeat != nil || stream.closed {
+		return
+	}
+	stop := make(chan struct{})
+	stream.heartbeat = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-stream.done:
+				return
+			case <-ticker.C:
+				if err := stream.Comment("heartbeat"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the heartbeat, events can not be sent after this
+func (stream *EventStream) Close() {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.closed {
+		return
+	}
+	stream.closed = true
+	if stream.heartbeat != nil {
+		close(stream.heartbeat)
+	}
+}
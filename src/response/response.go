@@ -1,17 +1,186 @@
 package response
 
-import "github.com/cccaaannn/gohst/src/constant"
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/header"
+)
+
+// Conn is implemented by the server, it lets a response be written to the connection while the handler is still running
+type Conn interface {
+	// Writes the status line and headers of the response
+	WriteHead(res *Response) error
+	// Writes part of the body, framing it if needed
+	Write(p []byte) (int, error)
+	// Sends buffered data to the client
+	Flush() error
+	// Returns a channel that is closed when the client disconnects, the connection is not reused after this is called
+	CloseNotify() <-chan struct{}
+	// Takes over the connection, the server does not write to or close it afterwards
+	Hijack() (net.Conn, *bufio.ReadWriter, error)
+}
 
 type Response struct {
 	Body       string
-	Headers    map[string]string
+	Headers    header.Header
+	Trailers   header.Header
 	StatusCode constant.HTTPStatusCode
+
+	conn        Conn
+	headersSent bool
+	onClose     []func()
+	reader      io.Reader
 }
 
 func CreateOkResponse() *Response {
 	return &Response{
-		Headers:    make(map[string]string),
+		Headers:    header.CreateHeader(),
+		Trailers:   header.CreateHeader(),
 		Body:       "",
 		StatusCode: constant.OkStatus,
 	}
 }
+
+// Attach connects the response to a server connection, after this writes are streamed to the client
+func (res *Response) Attach(conn Conn) {
+	res.conn = conn
+}
+
+// HeadersSent reports whether the status line and headers are already sent, after that changing them has no effect
+func (res *Response) HeadersSent() bool {
+	return res.headersSent
+}
+
+func (res *Response) sendHeaders() error {
+	if res.headersSent {
+		return nil
+	}
+	res.headersSent = true
+
+	if err := res.conn.WriteHead(res); err != nil {
+		return err
+	}
+
+	// Body set before the first write is sent first so nothing is lost
+	if res.Body != "" {
+		body := res.Body
+		res.Body = ""
+		if _, err := res.conn.Write([]byte(body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON sets the status code and the body to v encoded as JSON, the body is left as it is if v can not be encoded
+func (res *Response) JSON(status constant.HTTPStatusCode, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	res.StatusCode = status
+	res.Headers.Set(constant.ContentTypeHeader.String(), constant.ApplicationJson.String())
+	res.Body = string(body)
+	return nil
+}
+
+// SetBytes sets the body from b, any byte sequence is sent as it is
+func (res *Response) SetBytes(b []byte) {
+	res.Body = string(b)
+}
+
+// SetReader sets a body that is streamed from reader after the handler returns, readers that are also closers are closed after
+// With a negative length the length is unknown and the body is sent with chunked encoding
+func (res *Response) SetReader(reader io.Reader, length int64) {
+	res.reader = reader
+	if length >= 0 {
+		res.Headers.Set(constant.ContentLengthHeader.String(), strconv.FormatInt(length, 10))
+	}
+}
+
+// Send writes the body set with SetReader, the server calls it after the handler returns
+func (res *Response) Send() error {
+	reader := res.reader
+	if reader == nil {
+		return nil
+	}
+	res.reader = nil
+
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(writerOnly{res}, reader)
+	return err
+}
+
+// Hides other methods of the writer so io.Copy always goes through Write
+type writerOnly struct {
+	io.Writer
+}
+
+// Write streams p to the client, headers are sent on the first write
+// Without a Content-Length header the body is sent with chunked encoding
+// If the response is not attached to a connection p is appended to the Body
+func (res *Response) Write(p []byte) (int, error) {
+	if res.conn == nil {
+		res.Body += string(p)
+		return len(p), nil
+	}
+
+	if err := res.sendHeaders(); err != nil {
+		return 0, err
+	}
+	return res.conn.Write(p)
+}
+
+func (res *Response) WriteString(s string) (int, error) {
+	return res.Write([]byte(s))
+}
+
+// Flush sends the headers if they are not sent yet and everything written so far to the client
+func (res *Response) Flush() error {
+	if res.conn == nil {
+		return nil
+	}
+
+	if err := res.sendHeaders(); err != nil {
+		return err
+	}
+	return res.conn.Flush()
+}
+
+// CloseNotify returns a channel that is closed when the client disconnects, it is nil if the response is not attached to a connection
+func (res *Response) CloseNotify() <-chan struct{} {
+	if res.conn == nil {
+		return nil
+	}
+	return res.conn.CloseNotify()
+}
+
+// Hijack hands the underlying connection to the handler, used for protocols like websocket after switching protocols
+// Reader may contain data the client already sent, it should be used instead of reading the connection directly
+func (res *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if res.conn == nil {
+		return nil, nil, errors.New("response is not attached to a connection")
+	}
+	if res.headersSent {
+		return nil, nil, errors.New("headers are already sent")
+	}
+	return res.conn.Hijack()
+}
+
+// Close stops background writers of the response like event stream heartbeats, the server calls it after the handler returns
+func (res *Response) Close() {
+	onClose := res.onClose
+	res.onClose = nil
+	for _, fn := range onClose {
+		fn()
+	}
+}
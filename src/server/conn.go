@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+)
+
+// Tracks open connections and whether they are idle, so stopping the server can close idle keep-alive connections
+func (sv *Server) trackConn(conn net.Conn, add bool) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.conns == nil {
+		sv.conns = make(map[net.Conn]bool)
+	}
+	// New connections count as idle until the first byte of a request arrives
+	if add && sv.stopping {
+		conn.Close()
+	} else if add {
+		sv.conns[conn] = true
+	} else {
+		delete(sv.conns, conn)
+	}
+}
+
+// Marks the connection as idle, returns false if the server is stopping and the connection should be closed instead
+func (sv *Server) setConnIdle(conn net.Conn, idle bool) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if idle && sv.stopping {
+		return false
+	}
+	sv.conns[conn] = idle
+	return true
+}
+
+// Closes idle connections, returns whether there are no connections left
+func (sv *Server) closeIdleConns() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	for conn, idle := range sv.conns {
+		if idle {
+			conn.Close()
+			delete(sv.conns, conn)
+		}
+	}
+	return len(sv.conns) == 0
+}
+
+// HTTP/1.1 connections are persistent unless the client asks to close, HTTP/1.0 connections only if the client asks to keep them alive
+func (sv *Server) shouldKeepAlive(req *request.Request, served int) bool {
+	if sv.maxRequestsPerConnection > 0 && served >= sv.maxRequestsPerConnection {
+		return false
+	}
+
+	// Body framed by both headers may be read differently by a proxy in front, what follows it can not be trusted
+	if req.Headers.Has(constant.TransferEncodingHeader.String()) && req.Headers.Has(constant.ContentLengthHeader.String()) {
+		return false
+	}
+
+	switch req.Protocol {
+	case constant.HTTPVersion:
+		return !req.Headers.HasToken(constant.ConnectionHeader.String(), "close")
+	case constant.HTTPVersion10:
+		return req.Headers.HasToken(constant.ConnectionHeader.String(), "keep-alive")
+	default:
+		return false
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Reads the request body within the read deadline, the deadline is only set while reading so it does not limit the handler
+type deadlineReader struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	deadline time.Time
+}
+
+func (body *deadlineReader) Read(p []byte) (int, error) {
+	body.conn.SetReadDeadline(body.deadline)
+	defer body.conn.SetReadDeadline(time.Time{})
+	return body.reader.Read(p)
+}
+
+func (body *deadlineReader) ReadLine() ([]byte, bool, error) {
+	body.conn.SetReadDeadline(body.deadline)
+	defer body.conn.SetReadDeadline(time.Time{})
+	return body.reader.ReadLine()
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+)
+
+// ErrorHandler fills the response sent for a request that could not be read, status code is already set from the error
+type ErrorHandler func(res *response.Response, err error)
+
+// Sets the handler that writes the body of error responses, they are sent without a body if it is not set
+func (sv *Server) SetErrorHandler(errorHandler ErrorHandler) {
+	sv.errorHandler = errorHandler
+}
+
+// Status code a request reading error is answered with, false if the client is gone or can not be answered
+func errorStatus(err error) (constant.HTTPStatusCode, bool) {
+	if status, ok := requestErrorStatus(err); ok {
+		return status, true
+	}
+	if isTimeout(err) {
+		return constant.RequestTimeoutStatus, true
+	}
+	return 0, false
+}
+
+// Status code of the errors the request package returns for what the client sent, other errors are not the client's fault
+func requestErrorStatus(err error) (constant.HTTPStatusCode, bool) {
+	switch {
+	case errors.Is(err, request.ErrBadRequestLine),
+		errors.Is(err, request.ErrBadHeader),
+		errors.Is(err, request.ErrInvalidContentLength),
+		errors.Is(err, request.ErrMalformedChunk),
+		errors.Is(err, request.ErrInvalidJSON),
+		errors.Is(err, request.ErrMalformedForm):
+		return constant.BadRequestStatus, true
+	case errors.Is(err, request.ErrUnsupportedVersion):
+		return constant.HttpVersionNotSupportedStatus, true
+	case errors.Is(err, request.ErrUnsupportedTransferEncoding):
+		return constant.NotImplementedStatus, true
+	case errors.Is(err, request.ErrRequestLineTooLong):
+		return constant.UriTooLongStatus, true
+	case errors.Is(err, request.ErrHeaderTooLarge):
+		return constant.RequestHeaderFieldsTooLargeStatus, true
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return constant.PayloadTooLargeStatus, true
+	case errors.Is(err, request.ErrUnsupportedMediaType):
+		return constant.UnsupportedMediaTypeStatus, true
+	default:
+		return 0, false
+	}
+}
+
+// Handler answered unless it left the status and body as they were created, its answer to a body it could not read is kept
+func handlerAnswered(res *response.Response) bool {
+	return res.StatusCode != constant.OkStatus || res.Body != ""
+}
+
+func (sv *Server) createErrorResponse(status constant.HTTPStatusCode, err error) *response.Response {
+	res := response.CreateOkResponse()
+	res.StatusCode = status
+	if sv.errorHandler != nil {
+		sv.errorHandler(res, err)
+	}
+	return res
+}
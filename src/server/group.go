@@ -0,0 +1,54 @@
+package server
+
+import "strings"
+
+// Group registers handlers under a shared path prefix with its own middlewares, groups can be nested
+type Group struct {
+	server      *Server
+	parent      *Group
+	prefix      string
+	middlewares []Middleware
+}
+
+func (sv *Server) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		server:      sv,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+	}
+}
+
+// Group creates a child group, its prefix is appended to the parent prefix and its middlewares run after the parent middlewares
+func (group *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		server:      group.server,
+		parent:      group,
+		prefix:      strings.TrimSuffix(prefix, "/"),
+		middlewares: middlewares,
+	}
+}
+
+// Use adds a middleware that only runs for handlers of this group and its child groups
+func (group *Group) Use(middleware Middleware) {
+	group.middlewares = append(group.middlewares, middleware)
+}
+
+// AddHandler registers a handler with the group prefix, "GET /users" in group "/api" handles "GET /api/users"
+func (group *Group) AddHandler(requestPattern string, handlerFunc HandlerFunc, middlewares ...Middleware) *Route {
+	return group.server.addHandler(group, requestPattern, handlerFunc, middlewares)
+}
+
+func (group *Group) fullPrefix() string {
+	if group.parent == nil {
+		return group.prefix
+	}
+	return group.parent.fullPrefix() + group.prefix
+}
+
+// Middlewares of the group and its parents, parents first
+func (group *Group) chain() []Middleware {
+	if group.parent == nil {
+		return append([]Middleware{}, group.middlewares...)
+	}
+	return append(group.parent.chain(), group.middlewares...)
+}
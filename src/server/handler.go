@@ -1,6 +1,7 @@
 package server
 
 import (
+	"github.com/cccaaannn/gohst/src/constant"
 	"github.com/cccaaannn/gohst/src/request"
 	"github.com/cccaaannn/gohst/src/response"
 	"github.com/cccaaannn/gohst/src/url"
@@ -12,4 +13,50 @@ type handler struct {
 	path        url.Path
 	method      string
 	handlerFunc HandlerFunc
+	group       *Group
+	middlewares []Middleware
+	limits      *request.Limits
+}
+
+// Route is a registered handler, it is returned when adding a handler so it can be configured further
+type Route struct {
+	server  *Server
+	handler *handler
+}
+
+// Name registers the route under the name so its URL can be built with Server.URL, names must be unique
+func (route *Route) Name(name string) *Route {
+	route.server.nameRoute(name, route.handler)
+	return route
+}
+
+// SetLimits sets request limits for this route, zero fields fall back to the server limits
+// Request line and headers are read before routing so their route limits can only be stricter than the server limits
+func (route *Route) SetLimits(limits request.Limits) *Route {
+	route.handler.limits = &limits
+	return route
+}
+
+// Handlers registered for the same path pattern, handler with an empty method accepts every method
+type route struct {
+	handlers map[string]*handler
+}
+
+func (r *route) accepts(method string) bool {
+	_, ok := r.handler(method)
+	return ok
+}
+
+// Handler registered for the method wins over the one that accepts every method, HEAD falls back to GET
+func (r *route) handler(method string) (*handler, bool) {
+	if handler, ok := r.handlers[method]; ok {
+		return handler, true
+	}
+	if method == constant.HeadMethod {
+		if handler, ok := r.handlers[constant.GetMethod]; ok {
+			return handler, true
+		}
+	}
+	handler, ok := r.handlers[""]
+	return handler, ok
 }
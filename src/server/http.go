@@ -0,0 +1,241 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/header"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+)
+
+// Writes a response through a net/http response writer, connection handling is left to net/http
+type httpResponseStream struct {
+	server   *Server
+	writer   http.ResponseWriter
+	request  *http.Request
+	hijacked bool
+}
+
+func (sv *Server) copyHeaders(res *response.Response, httpHeader http.Header) {
+	for key, values := range sv.getMergedHeaders(res, true) {
+		httpHeader[key] = values
+	}
+	httpHeader.Del(constant.ConnectionHeader.String())
+}
+
+func (stream *httpResponseStream) WriteHead(res *response.Response) error {
+	header := stream.writer.Header()
+	stream.server.copyHeaders(res, header)
+
+	// Length is only known if the handler set it, net/http chooses the framing otherwise
+	if !res.Headers.Has(constant.ContentLengthHeader.String()) {
+		header.Del(constant.ContentLengthHeader.String())
+	}
+
+	stream.writer.WriteHeader(int(res.StatusCode))
+	return nil
+}
+
+func (stream *httpResponseStream) Write(p []byte) (int, error) {
+	return stream.writer.Write(p)
+}
+
+func (stream *httpResponseStream) Flush() error {
+	return http.NewResponseController(stream.writer).Flush()
+}
+
+func (stream *httpResponseStream) CloseNotify() <-chan struct{} {
+	return stream.request.Context().Done()
+}
+
+func (stream *httpResponseStream) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(stream.writer).Hijack()
+	if err == nil {
+		stream.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// ServeHTTP lets the server be used as an http.Handler, for example mounted in an http.Server or tested with httptest
+func (sv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Head limits are left to net/http
+	limits := sv.requestLimits(&request.Request{Method: r.Method, RawPath: r.URL.RequestURI()})
+	reader := io.Reader(r.Body)
+	if limits.MaxBodySize > 0 {
+		reader = http.MaxBytesReader(w, r.Body, limits.MaxBodySize)
+	}
+
+	// Both use the same canonical names
+	headers := header.Header(r.Header.Clone())
+	headers.Set("Host", r.Host)
+
+	req := &request.Request{
+		Method:          r.Method,
+		Path:            r.URL.RequestURI(),
+		RawPath:         r.URL.RequestURI(),
+		Protocol:        r.Proto,
+		RemoteAddr:      r.RemoteAddr,
+		Body:            request.CreateBody(httpBodyReader{reader: reader}),
+		Headers:         headers,
+		Trailers:        header.Header(r.Trailer),
+		ChunkExtensions: make([]request.ChunkExtension, 0),
+		Context:         make(map[string]any),
+	}
+
+	res := response.CreateOkResponse()
+	stream := &httpResponseStream{server: sv, writer: w, request: r}
+	res.Attach(stream)
+
+	sv.dispatch(req, res)
+
+	if stream.hijacked {
+		return
+	}
+
+	// Anything net/http fails to read is the client's fault, it is answered unless the handler did
+	if err := req.Body.Discard(); err != nil && !res.HeadersSent() && !handlerAnswered(res) {
+		status, ok := errorStatus(err)
+		if !ok {
+			status = constant.BadRequestStatus
+		}
+		res = sv.createErrorResponse(status, err)
+	}
+
+	// Trailers set after the body is written are sent with the prefix net/http expects
+	if res.HeadersSent() {
+		for key, values := range res.Trailers {
+			w.Header()[http.TrailerPrefix+key] = values
+		}
+		return
+	}
+
+	// Handler set headers that can not be sent, nothing it set is trusted
+	if err := res.Headers.Validate(); err != nil {
+		fmt.Println("Error writing response:", err)
+		res = response.CreateOkResponse()
+		res.StatusCode = constant.InternalServerErrorStatus
+	}
+
+	sv.copyHeaders(res, w.Header())
+	w.WriteHeader(int(res.StatusCode))
+	io.WriteString(w, res.Body)
+}
+
+// Maps the errors of a body read by net/http to the request errors
+type httpBodyReader struct {
+	reader io.Reader
+}
+
+func (body httpBodyReader) Read(p []byte) (int, error) {
+	n, err := body.reader.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = request.ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// Collects what an http.Handler writes into a gohst response, flushing switches the response to streaming
+type httpResponseWriter struct {
+	res         *response.Response
+	header      http.Header
+	wroteHeader bool
+	streaming   bool
+}
+
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *httpResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	for key, values := range w.header {
+		w.res.Headers[header.CanonicalName(key)] = append([]string(nil), values...)
+	}
+	w.res.StatusCode = constant.HTTPStatusCode(statusCode)
+}
+
+func (w *httpResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	if w.streaming {
+		return w.res.Write(p)
+	}
+	w.res.Body += string(p)
+	return len(p), nil
+}
+
+func (w *httpResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	w.streaming = true
+	w.res.Flush()
+}
+
+func (w *httpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.res.Hijack()
+}
+
+func toHTTPRequest(req *request.Request) (*http.Request, error) {
+	requestURL, err := neturl.ParseRequestURI(req.RawPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &http.Request{
+		Method:        req.Method,
+		URL:           requestURL,
+		Proto:         req.Protocol,
+		Header:        http.Header(req.Headers.Clone()),
+		Trailer:       http.Header(req.Trailers.Clone()),
+		Body:          req.Body,
+		ContentLength: 0,
+		RemoteAddr:    req.RemoteAddr,
+		RequestURI:    req.RawPath,
+	}
+
+	var ok bool
+	if r.ProtoMajor, r.ProtoMinor, ok = http.ParseHTTPVersion(req.Protocol); !ok {
+		r.ProtoMajor, r.ProtoMinor = 1, 1
+	}
+
+	// net/http keeps the host and body length outside of the headers, chunked bodies are already decoded and have no known length
+	r.Host = r.Header.Get("Host")
+	r.Header.Del("Host")
+	if r.Header.Get(constant.TransferEncodingHeader.String()) != "" {
+		r.ContentLength = -1
+		r.Header.Del(constant.TransferEncodingHeader.String())
+	} else if contentLength, err := strconv.ParseInt(r.Header.Get(constant.ContentLengthHeader.String()), 10, 64); err == nil {
+		r.ContentLength = contentLength
+	}
+
+	return r, nil
+}
+
+// WrapHTTPHandler lets any http.Handler or http.HandlerFunc be added as a gohst handler
+func WrapHTTPHandler(handler http.Handler) HandlerFunc {
+	return func(req *request.Request, res *response.Response) {
+		r, err := toHTTPRequest(req)
+		if err != nil {
+			res.StatusCode = constant.BadRequestStatus
+			return
+		}
+
+		w := &httpResponseWriter{res: res, header: make(http.Header)}
+		handler.ServeHTTP(w, r)
+
+		// Handler may only set headers without writing
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/cccaaannn/gohst/src/binding"
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+	"github.com/cccaaannn/gohst/src/validation"
+)
+
+// HTTPError is returned from typed handlers to answer with a status code, other errors are answered with 500 Internal Server Error
+type HTTPError struct {
+	StatusCode constant.HTTPStatusCode
+	Message    string
+}
+
+func CreateHTTPError(status constant.HTTPStatusCode, message string) *HTTPError {
+	return &HTTPError{StatusCode: status, Message: message}
+}
+
+func (err *HTTPError) Error() string {
+	return fmt.Sprintf("%d %s", err.StatusCode, err.Message)
+}
+
+// Body of the error responses sent by WriteJSONError
+type errorBody struct {
+	Error  string                  `json:"error"`
+	Fields []validation.FieldError `json:"fields,omitempty"`
+}
+
+// JSONHandler creates a handler that decodes the request body into In, binds its tagged fields, validates it and answers with the returned Out encoded as JSON
+// Requests without a body leave the body fields of In at their zero values, errors are answered by WriteJSONError
+// Invalid binding and validate tags of In panic when the handler is created
+func JSONHandler[In any, Out any](fn func(req *request.Request, in In) (Out, error)) HandlerFunc {
+	bind := reflect.TypeFor[In]().Kind() == reflect.Struct
+	var zero In
+	if bind {
+		binding.Prepare(zero)
+	}
+	validation.Prepare(zero)
+
+	return func(req *request.Request, res *response.Response) {
+		var in In
+		if req.HasBody() {
+			if err := req.DecodeJSON(&in); err != nil {
+				WriteJSONError(res, err)
+				return
+			}
+		}
+
+		// Path params, query, headers and cookies win over the body
+		if bind {
+			if err := binding.Bind(req, &in); err != nil {
+				WriteJSONError(res, err)
+				return
+			}
+		}
+		if err := validation.Validate(in); err != nil {
+			WriteJSONError(res, err)
+			return
+		}
+
+		out, err := fn(req, in)
+		if err != nil {
+			WriteJSONError(res, err)
+			return
+		}
+		if err := res.JSON(constant.OkStatus, out); err != nil {
+			WriteJSONError(res, err)
+		}
+	}
+}
+
+// WriteJSONError answers with the error as JSON, validation errors with 422 Unprocessable Entity and a list of the fields
+// Binding errors are answered with 400 Bad Request and a list of the fields, errors of what the client sent like ErrInvalidJSON get their own status
+// Other errors than HTTPError, timeouts included, are answered with 500 Internal Server Error and a generic message so their details do not leak
+func WriteJSONError(res *response.Response, err error) {
+	var httpErr *HTTPError
+	var validationErrs validation.Errors
+	var bindingErrs binding.Errors
+	switch {
+	case errors.As(err, &httpErr):
+		res.JSON(httpErr.StatusCode, errorBody{Error: httpErr.Message})
+	case errors.As(err, &validationErrs):
+		res.JSON(constant.UnprocessableEntityStatus, errorBody{Error: "validation failed", Fields: validationErrs})
+	case errors.As(err, &bindingErrs):
+		res.JSON(constant.BadRequestStatus, errorBody{Error: "invalid request parameters", Fields: bindingErrs})
+	default:
+		// Timeouts of handlers are their own, only what the client sent is answered with a client error
+		if status, ok := requestErrorStatus(err); ok {
+			res.JSON(status, errorBody{Error: err.Error()})
+			return
+		}
+
+		fmt.Println("Error handling request:", err)
+		res.JSON(constant.InternalServerErrorStatus, errorBody{Error: "internal server error"})
+	}
+}
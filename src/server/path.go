@@ -0,0 +1,68 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+	"github.com/cccaaannn/gohst/src/url"
+)
+
+// PathOptions decide how the request path is prepared before routing
+type PathOptions struct {
+	// Routes the cleaned path, repeated slashes are collapsed and "." and ".." segments are resolved
+	Clean bool
+	// Status code of the redirect sent when the path only matches a route with or without a trailing slash
+	// Use 301 Moved Permanently or 308 Permanent Redirect, zero answers such requests with 404 Not Found
+	TrailingSlashRedirect constant.HTTPStatusCode
+}
+
+func defaultPathOptions() PathOptions {
+	return PathOptions{Clean: true}
+}
+
+// Sets how request paths are cleaned and redirected before routing
+func (sv *Server) SetPathOptions(options PathOptions) {
+	sv.pathOptions = options
+}
+
+// Path the request is routed with, the raw path is kept on the request
+func (sv *Server) routePath(rawPath string) (string, string) {
+	path, query := url.SplitQuery(rawPath)
+	if sv.pathOptions.Clean {
+		path = url.CleanPath(path)
+	}
+	return path, query
+}
+
+// Redirects to the same path with the trailing slash added or removed if only that one has a route
+func (sv *Server) redirectTrailingSlash(path string, query string, res *response.Response) bool {
+	status := sv.pathOptions.TrailingSlashRedirect
+	if status == 0 || path == "/" {
+		return false
+	}
+
+	other := path + "/"
+	if strings.HasSuffix(path, "/") {
+		other = strings.TrimSuffix(path, "/")
+	}
+	if _, _, ok := sv.routes.Lookup(other, make([]url.Param, 0, 4), func(*route) bool { return true }); !ok {
+		return false
+	}
+
+	if query != "" {
+		other += "?" + query
+	}
+	res.StatusCode = status
+	res.Headers.Set(constant.LocationHeader.String(), other)
+	return true
+}
+
+// Sets the cleaned path and parsed query on the request, the raw query is returned for redirects
+func (sv *Server) preparePath(req *request.Request) string {
+	path, query := sv.routePath(req.RawPath)
+	req.Path = path
+	req.Query = url.ParseQuery(query)
+	return query
+}
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/header"
 	"github.com/cccaaannn/gohst/src/request"
 	"github.com/cccaaannn/gohst/src/response"
 	"github.com/cccaaannn/gohst/src/url"
@@ -14,42 +23,124 @@ import (
 )
 
 type Server struct {
-	handlers    []handler
-	headers     map[string]string
-	middlewares []Middleware
+	routes                   *url.Tree[*route]
+	headers                  header.Header
+	middlewares              []Middleware
+	readHeaderTimeout        time.Duration
+	readTimeout              time.Duration
+	writeTimeout             time.Duration
+	idleTimeout              time.Duration
+	maxRequestsPerConnection int
+	limits                   request.Limits
+	errorHandler             ErrorHandler
+	pathOptions              PathOptions
+	namedRoutes              map[string]*handler
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]bool
+	stopping  bool
+	done      chan struct{}
+	err       error
 }
 
 func CreateServer() *Server {
 	return &Server{
-		handlers: make([]handler, 0),
-		headers:  getDefaultHeaders(),
+		routes:      url.CreateTree[*route](),
+		headers:     getDefaultHeaders(),
+		limits:      request.DefaultLimits(),
+		pathOptions: defaultPathOptions(),
+		done:        make(chan struct{}),
 	}
 }
 
-func (sv *Server) AddHandler(requestPattern string, handlerFunc HandlerFunc) {
+// Middlewares given here only run for this handler, after the server and group middlewares
+func (sv *Server) AddHandler(requestPattern string, handlerFunc HandlerFunc, middlewares ...Middleware) *Route {
+	return sv.addHandler(nil, requestPattern, handlerFunc, middlewares)
+}
+
+func (sv *Server) addHandler(group *Group, requestPattern string, handlerFunc HandlerFunc, middlewares []Middleware) *Route {
 	pathText, method, ok := util.ParseRequestPattern(requestPattern)
 	if !ok {
 		panic(fmt.Sprintf("Cannot add handler with request pattern of %s\n", requestPattern))
 	}
+	if group != nil {
+		pathText = group.fullPrefix() + pathText
+	}
 
 	path := url.CreatePath(pathText)
-	handler := handler{
+	added := &handler{
 		path:        path,
 		method:      method,
 		handlerFunc: handlerFunc,
+		group:       group,
+		middlewares: middlewares,
 	}
 
-	sv.handlers = append(sv.handlers, handler)
+	// Patterns with optional segments are added once for every path they expand to
+	for _, expanded := range path.Expand() {
+		leaf := sv.routes.Insert(expanded)
+		if *leaf == nil {
+			*leaf = &route{handlers: make(map[string]*handler)}
+		}
+		if _, ok := (*leaf).handlers[method]; ok {
+			panic(fmt.Sprintf("Handler with request pattern of %s is already added\n", requestPattern))
+		}
+		(*leaf).handlers[method] = added
+	}
+	return &Route{server: sv, handler: added}
 }
 
 func (sv *Server) SetHeaders(headers map[string]string) {
-	sv.headers = headers
+	sv.headers = header.CreateHeader()
+	for key, val := range headers {
+		sv.headers.Set(key, val)
+	}
+	if err := sv.headers.Validate(); err != nil {
+		panic(fmt.Sprintf("Cannot set default headers, %v\n", err))
+	}
 }
 
 func (sv *Server) Use(middleware Middleware) {
 	sv.middlewares = append(sv.middlewares, middleware)
 }
 
+// Sets how long reading the request line and headers can take, zero waits forever
+// Clients that send a part of the request but not the rest in time are answered with 408 Request Timeout
+func (sv *Server) SetReadHeaderTimeout(timeout time.Duration) {
+	sv.readHeaderTimeout = timeout
+}
+
+// Sets how long reading the whole request including the body can take, zero waits forever
+func (sv *Server) SetReadTimeout(timeout time.Duration) {
+	sv.readTimeout = timeout
+}
+
+// Sets how long the handler has to write the response once the request is read, zero waits forever
+func (sv *Server) SetWriteTimeout(timeout time.Duration) {
+	sv.writeTimeout = timeout
+}
+
+// Sets how long a keep-alive connection waits for the next request, the read timeout is used if it is zero
+func (sv *Server) SetIdleTimeout(timeout time.Duration) {
+	sv.idleTimeout = timeout
+}
+
+// Sets the max size of a request body, zero means no limit
+func (sv *Server) SetMaxBodySize(size int64) {
+	sv.limits.MaxBodySize = size
+}
+
+// Sets the request size limits of every route, zero fields mean no limit
+func (sv *Server) SetLimits(limits request.Limits) {
+	sv.limits = limits
+}
+
+// Sets how many requests are served over a single connection before it is closed, zero means no limit
+func (sv *Server) SetMaxRequestsPerConnection(max int) {
+	sv.maxRequestsPerConnection = max
+}
+
 func (sv *Server) ListenAndServeTLS(address string, certFile string, keyFile string) (chan struct{}, error) {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
 	if err != nil {
@@ -76,31 +167,32 @@ func (sv *Server) ListenAndServe(address string) (chan struct{}, error) {
 	return sv.listenAndServe(listener)
 }
 
+// Closing the returned channel shuts the server down gracefully without a deadline, use Shutdown to set one
 func (sv *Server) listenAndServe(listener net.Listener) (chan struct{}, error) {
-	var once sync.Once
-	var wg sync.WaitGroup
+	if !sv.trackListener(listener, true) {
+		listener.Close()
+		return nil, ErrServerClosed
+	}
 	stop := make(chan struct{})
 
 	go func() {
+		defer sv.trackListener(listener, false)
 		for {
 			conn, err := listener.Accept()
 			if err != nil {
-				// Non blocking select to check errors and stop signal
-				select {
-				// When listener is closed it errors, so we check if channel is closed
-				case <-stop:
+				// When listener is closed by shutdown it errors, so we check if the server is stopping
+				if sv.isStopping() {
 					return
-				// If it's not closed we continue with logging the error
-				default:
-					fmt.Println("Error accepting: ", err.Error())
-					continue
 				}
+				// If it's not stopping we continue with logging the error
+				fmt.Println("Error accepting: ", err.Error())
+				continue
 			}
 
 			// Handle new connection in a separate goroutine
-			wg.Add(1)
+			sv.trackConn(conn, true)
 			go func() {
-				defer wg.Done()
+				defer sv.trackConn(conn, false)
 				sv.handleConnection(conn)
 			}()
 		}
@@ -108,133 +200,413 @@ func (sv *Server) listenAndServe(listener net.Listener) (chan struct{}, error) {
 
 	// Goroutine to handle gracefully shuting down the server
 	go func() {
-		<-stop
-		once.Do(func() {
-			listener.Close()
-			wg.Wait()
-			fmt.Println("Server stopped")
-		})
+		select {
+		case <-stop:
+			sv.Shutdown(context.Background())
+		case <-sv.done:
+		}
 	}()
 
 	return stop, nil
 }
 
-func getDefaultHeaders() map[string]string {
-	return map[string]string{
-		constant.ServerHeader.String():      constant.ServerName,
-		constant.ConnectionHeader.String():  "close",
-		constant.ContentTypeHeader.String(): constant.TextHtml.String(),
+func getDefaultHeaders() header.Header {
+	headers := header.CreateHeader()
+	headers.Set(constant.ServerHeader.String(), constant.ServerName)
+	headers.Set(constant.ContentTypeHeader.String(), constant.TextHtml.String())
+	return headers
+}
+
+func (server *Server) matchHandler(path string, method string) (*handler, map[string]string, bool) {
+	route, params, ok := server.routes.Lookup(path, make([]url.Param, 0, 4), func(r *route) bool {
+		return r.accepts(method)
+	})
+	if !ok {
+		return nil, nil, false
 	}
+
+	paramMap := make(map[string]string, len(params))
+	for _, param := range params {
+		// Params are matched on the escaped path so an encoded slash stays in its segment, values are decoded afterwards
+		paramMap[param.Key] = url.DecodeParam(param.Value)
+	}
+	handler, _ := route.handler(method)
+	return handler, paramMap, true
+}
+
+func (server *Server) hasExplicitHandler(path string, method string) bool {
+	_, _, ok := server.routes.Lookup(path, make([]url.Param, 0, 4), func(r *route) bool {
+		_, ok := r.handlers[method]
+		return ok
+	})
+	return ok
 }
 
-func (server *Server) matchHandler(path string, method string) (handler, map[string]string, bool) {
-	for _, handler := range server.handlers {
-		params, ok := handler.path.Match(path)
-		if ok && (handler.method == "" || handler.method == method) {
-			return handler, params, true
+// Collects the methods of every route matching the path, handlers without a method are not listed since they accept every method
+func (server *Server) allowedMethods(path string) []string {
+	methods := make([]string, 0)
+	server.routes.Lookup(path, make([]url.Param, 0, 4), func(r *route) bool {
+		for method := range r.handlers {
+			if method != "" && !slices.Contains(methods, method) {
+				methods = append(methods, method)
+			}
 		}
+		return false
+	})
+
+	if slices.Contains(methods, constant.GetMethod) && !slices.Contains(methods, constant.HeadMethod) {
+		methods = append(methods, constant.HeadMethod)
 	}
-	return handler{}, nil, false
+	if len(methods) > 0 && !slices.Contains(methods, constant.OptionsMethod) {
+		methods = append(methods, constant.OptionsMethod)
+	}
+	sort.Strings(methods)
+	return methods
 }
 
-func (server *Server) getMergedHeaders(response *response.Response) map[string]string {
-	contentLength := len([]byte(response.Body))
+func (server *Server) getMergedHeaders(response *response.Response, keepAlive bool) header.Header {
+	contentLength := len(response.Body)
 
-	requestHeaders := map[string]string{
-		constant.DateHeader.String():          util.GetHttpTime(),
-		constant.ContentLengthHeader.String(): fmt.Sprintf("%d", contentLength),
+	connection := "close"
+	if keepAlive {
+		connection = "keep-alive"
 	}
 
-	mergedHeaders := make(map[string]string)
-
 	// Add default headers
-	for key, val := range server.headers {
-		mergedHeaders[key] = val
-	}
+	mergedHeaders := server.headers.Clone()
 
 	// Request headers override default headers
-	for key, val := range requestHeaders {
-		mergedHeaders[key] = val
-	}
+	mergedHeaders.Set(constant.DateHeader.String(), util.GetHttpTime())
+	mergedHeaders.Set(constant.ContentLengthHeader.String(), fmt.Sprintf("%d", contentLength))
+	mergedHeaders.Set(constant.ConnectionHeader.String(), connection)
 
 	// User headers override request headers
-	for key, val := range response.Headers {
-		mergedHeaders[key] = val
+	for key, values := range response.Headers {
+		mergedHeaders[header.CanonicalName(key)] = append([]string(nil), values...)
+	}
+
+	// Responses without content can not declare a length
+	if response.StatusCode < constant.OkStatus || response.StatusCode == constant.NoContentStatus {
+		mergedHeaders.Del(constant.ContentLengthHeader.String())
 	}
 
 	return mergedHeaders
 }
 
-func (server *Server) buildResponseString(response *response.Response) string {
-	responseStr := "" +
-		"%s\r\n" +
-		"%s" +
-		"\r\n%s"
+// Server headers come first and headers the handler set after them, both sorted so the order is the same for every response
+func orderHeaderNames(response *response.Response, mergedHeaders header.Header) []string {
+	serverNames := make([]string, 0, len(mergedHeaders))
+	userNames := make([]string, 0, len(response.Headers))
+	for name := range mergedHeaders {
+		if response.Headers.Has(name) {
+			userNames = append(userNames, name)
+		} else {
+			serverNames = append(serverNames, name)
+		}
+	}
+	sort.Strings(serverNames)
+	sort.Strings(userNames)
+	return append(serverNames, userNames...)
+}
+
+// Repeated headers are written as separate lines, headers that could inject other headers are rejected
+func (server *Server) buildResponseHead(response *response.Response, mergedHeaders header.Header) (string, error) {
+	if err := mergedHeaders.Validate(); err != nil {
+		return "", err
+	}
 
-	requestLineStr := fmt.Sprintf(
-		"%s %s %s",
+	var responseStr strings.Builder
+	fmt.Fprintf(&responseStr,
+		"%s %s %s\r\n",
 		constant.HTTPVersion, response.StatusCode.String(), response.StatusCode.Verb(),
 	)
 
-	mergedHeaders := server.getMergedHeaders(response)
-	mergedHeadersStr := ""
-	for key, val := range mergedHeaders {
-		mergedHeadersStr += fmt.Sprintf("%s: %s\r\n", key, val)
+	for _, name := range orderHeaderNames(response, mergedHeaders) {
+		for _, value := range mergedHeaders[name] {
+			fmt.Fprintf(&responseStr, "%s: %s\r\n", name, value)
+		}
 	}
+	responseStr.WriteString("\r\n")
 
-	responseStr = fmt.Sprintf(
-		responseStr,
-		requestLineStr,
-		mergedHeadersStr,
-		response.Body,
-	)
+	return responseStr.String(), nil
+}
 
-	return responseStr
+// Responses to HEAD requests have the same headers without the body, statuses that can not have a body never send it
+func (server *Server) buildResponseString(response *response.Response, mergedHeaders header.Header, withBody bool) (string, error) {
+	head, err := server.buildResponseHead(response, mergedHeaders)
+	if err != nil || !withBody || !response.StatusCode.AllowsBody() {
+		return head, err
+	}
+	return head + response.Body, nil
 }
 
 // The chain is constructed by iterating middleware slice in reverse order, by passing the next middleware to the current middleware
 // Ex: [middleware1, middleware2, middleware3] This slice will construct this chain -> middleware1(middleware2(middleware3(handlerFunc)))
-func (sv *Server) constructMiddlewareChain(handler HandlerFunc) HandlerFunc {
-	finalHandler := handler
-	for i := len(sv.middlewares) - 1; i >= 0; i-- {
-		finalHandler = sv.middlewares[i](finalHandler)
+// Middlewares run from the outermost to the innermost scope, server middlewares first, then groups from parent to child and route middlewares last
+func (sv *Server) constructMiddlewareChain(handler *handler) HandlerFunc {
+	middlewares := append([]Middleware{}, sv.middlewares...)
+	if handler.group != nil {
+		middlewares = append(middlewares, handler.group.chain()...)
+	}
+	middlewares = append(middlewares, handler.middlewares...)
+
+	finalHandler := handler.handlerFunc
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		finalHandler = middlewares[i](finalHandler)
 	}
 	return finalHandler
 }
 
 func (sv *Server) handleConnection(conn net.Conn) {
-	defer conn.Close()
+	hijacked := false
+	defer func() {
+		// Hijacked connections belong to the handler
+		if !hijacked {
+			conn.Close()
+		}
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	start := time.Now()
+	for served := 1; ; served++ {
+		// Wait for the first byte of the next request, only this wait counts as idle
+		// The first request of a connection is timed from the accept so clients can not hold a connection without sending anything
+		if served > 1 {
+			idleTimeout := sv.idleTimeout
+			if idleTimeout == 0 {
+				idleTimeout = sv.readTimeout
+			}
+			conn.SetReadDeadline(earliestDeadline(time.Now(), idleTimeout))
+		} else {
+			conn.SetReadDeadline(earliestDeadline(start, sv.readHeaderTimeout, sv.readTimeout))
+		}
+		if _, err := reader.Peek(1); err != nil {
+			if err != io.EOF && !isTimeout(err) && !errors.Is(err, net.ErrClosed) {
+				fmt.Println("Error reading request:", err)
+			}
+			return
+		}
+		if served > 1 {
+			start = time.Now()
+		}
+		sv.setConnIdle(conn, false)
+
+		req, err := sv.readRequest(conn, reader, start)
+		if err != nil {
+			if !isTimeout(err) {
+				fmt.Println("Error parsing request:", err)
+			}
+			sv.writeErrorResponse(conn, err)
+			return
+		}
+
+		// Handlers are not limited by the read deadline, closing notifications read from the connection while they run
+		conn.SetReadDeadline(time.Time{})
+		conn.SetWriteDeadline(earliestDeadline(time.Now(), sv.writeTimeout))
 
-	req, err := request.ParseRequest(conn)
+		req.RemoteAddr = conn.RemoteAddr().String()
+
+		keepAlive := sv.shouldKeepAlive(req, served)
+		keepAlive, hijacked = sv.serveRequest(conn, reader, writer, req, keepAlive)
+		if !keepAlive {
+			return
+		}
+
+		if !sv.setConnIdle(conn, true) {
+			return
+		}
+	}
+}
+
+// Earliest of the deadlines the non zero timeouts give from start, zero time if all timeouts are zero
+func earliestDeadline(start time.Time, timeouts ...time.Duration) time.Time {
+	var deadline time.Time
+	for _, timeout := range timeouts {
+		if timeout <= 0 {
+			continue
+		}
+		if candidate := start.Add(timeout); deadline.IsZero() || candidate.Before(deadline) {
+			deadline = candidate
+		}
+	}
+	return deadline
+}
+
+// Limits of the handler the request is routed to, fields the handler does not set fall back to the server limits
+func (sv *Server) requestLimits(req *request.Request) request.Limits {
+	path, _ := sv.routePath(req.RawPath)
+	handler, _, ok := sv.matchHandler(path, req.Method)
+	if !ok || handler.limits == nil {
+		return sv.limits
+	}
+	return sv.limits.Merge(*handler.limits)
+}
+
+// Reads the head within the read header timeout and the whole request within the read timeout, both are counted from start
+// The body is attached after routing so routes can have their own limits, the handler reads it from the connection
+func (sv *Server) readRequest(conn net.Conn, reader *bufio.Reader, start time.Time) (*request.Request, error) {
+	conn.SetReadDeadline(earliestDeadline(start, sv.readHeaderTimeout, sv.readTimeout))
+	req, err := request.ParseRequestHead(reader, sv.limits)
 	if err != nil {
-		fmt.Println("Error parsing request:", err)
+		return nil, err
+	}
+
+	limits := sv.requestLimits(req)
+	if err := limits.CheckHead(req); err != nil {
+		return nil, err
+	}
+
+	bodyReader := &deadlineReader{conn: conn, reader: reader, deadline: earliestDeadline(start, sv.readTimeout)}
+	if err := req.AttachBody(bodyReader, limits.MaxBodySize); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Answers requests that could not be parsed, the connection is closed afterwards since the rest of the stream can not be trusted
+func (sv *Server) writeErrorResponse(conn net.Conn, err error) {
+	status, ok := errorStatus(err)
+	if !ok {
 		return
 	}
+	res := sv.createErrorResponse(status, err)
+	responseStr, err := sv.buildResponseString(res, sv.getMergedHeaders(res, false), true)
+	if err != nil {
+		// Error handler set invalid headers, the status is sent without them
+		fmt.Println("Error writing response:", err)
+		res = response.CreateOkResponse()
+		res.StatusCode = status
+		responseStr, _ = sv.buildResponseString(res, sv.getMergedHeaders(res, false), true)
+	}
+
+	// Read deadline may have passed already, the answer gets its own write deadline
+	conn.SetWriteDeadline(earliestDeadline(time.Now(), sv.writeTimeout))
+
+	conn.Write([]byte(responseStr))
+}
+
+// Routes the request and runs the handler, the response is written by the caller unless the handler streamed it
+func (sv *Server) dispatch(req *request.Request, res *response.Response) {
+	// Uploads only live as long as the handler, responses streamed from them are sent before this
+	defer func() {
+		if err := req.RemoveFiles(); err != nil {
+			fmt.Println("Error removing uploaded files:", err)
+		}
+	}()
+
+	// Path cleaning and query parsing
+	query := sv.preparePath(req)
+	path := req.Path
 
-	// Query parsing
-	path, query := url.SplitQuery(req.Path)
-	req.Query = url.ParseQuery(query)
+	// Params are decoded after cleaning, a decoded ".." would pass straight into the handler
+	if url.HasEncodedDotSegment(path) {
+		res.StatusCode = constant.BadRequestStatus
+		res.Close()
+		return
+	}
 
 	// Path parsing
 	handler, params, matched := sv.matchHandler(path, req.Method)
 	req.Params = params
 
-	res := response.CreateOkResponse()
+	// Path exists but not for this method, OPTIONS is answered automatically unless there is a handler for it
+	var allowed []string
+	if !matched || (req.Method == constant.OptionsMethod && !sv.hasExplicitHandler(path, req.Method)) {
+		allowed = sv.allowedMethods(path)
+	}
 
-	if !matched {
-		res.StatusCode = constant.NotFoundStatus
-		responseStr := sv.buildResponseString(res)
-		conn.Write([]byte(responseStr))
+	if !matched && len(allowed) == 0 && sv.redirectTrailingSlash(path, query, res) {
+		res.Close()
 		return
 	}
 
-	// Construct middleware chain
-	finalHandler := sv.constructMiddlewareChain(handler.handlerFunc)
+	if len(allowed) > 0 {
+		res.Headers.Set(constant.AllowHeader.String(), strings.Join(allowed, ", "))
+		res.StatusCode = constant.MethodNotAllowedStatus
+		if req.Method == constant.OptionsMethod {
+			res.StatusCode = constant.NoContentStatus
+		}
+	} else if !matched {
+		res.StatusCode = constant.NotFoundStatus
+	} else {
+		// Construct middleware chain
+		finalHandler := sv.constructMiddlewareChain(handler)
+
+		// Call final handler, this is either the handler function or the middleware chain
+		finalHandler(req, res)
+
+		// Body set as a reader is streamed after the handler
+		if err := res.Send(); err != nil {
+			fmt.Println("Error writing response:", err)
+			if !res.HeadersSent() {
+				res.StatusCode = constant.InternalServerErrorStatus
+				res.Body = ""
+				res.Headers.Del(constant.ContentLengthHeader.String())
+			}
+		}
+	}
+	res.Close()
+}
 
-	// Call final handler, this is either the handler function or the middleware chain
-	finalHandler(req, res)
+// Serves a single request, returns whether the connection can be reused and whether the handler took it over
+func (sv *Server) serveRequest(conn net.Conn, reader *bufio.Reader, writer *bufio.Writer, req *request.Request, keepAlive bool) (bool, bool) {
+	res := response.CreateOkResponse()
+	stream := &responseStream{
+		server:    sv,
+		conn:      conn,
+		reader:    reader,
+		writer:    writer,
+		body:      req.Body,
+		protocol:  req.Protocol,
+		discard:   req.Method == constant.HeadMethod,
+		keepAlive: keepAlive,
+	}
+	res.Attach(stream)
 
-	responseStr := sv.buildResponseString(res)
+	sv.dispatch(req, res)
 
-	conn.Write([]byte(responseStr))
+	if stream.hijacked {
+		return false, true
+	}
+
+	// Rest of the body is read so the next request starts at the right place
+	if err := req.Body.Discard(); err != nil {
+		stream.keepAlive = false
+		if status, ok := errorStatus(err); ok && !res.HeadersSent() && !handlerAnswered(res) {
+			// Handler did not answer a request it could not read, otherwise its answer is sent and the connection closed
+			res = sv.createErrorResponse(status, err)
+		}
+	}
+
+	// Handler streamed the response, only the end of the body is left
+	if res.HeadersSent() {
+		if err := stream.finish(res); err != nil {
+			return false, false
+		}
+		return stream.keepAlive, false
+	}
+
+	mergedHeaders := sv.getMergedHeaders(res, stream.keepAlive)
+
+	// Handlers can close the connection by setting the header themselves
+	keepAlive = stream.keepAlive && !mergedHeaders.HasToken(constant.ConnectionHeader.String(), "close")
+
+	responseStr, err := sv.buildResponseString(res, mergedHeaders, req.Method != constant.HeadMethod)
+	if err != nil {
+		// Handler set headers that can not be sent, nothing it set is trusted
+		fmt.Println("Error writing response:", err)
+		res = response.CreateOkResponse()
+		res.StatusCode = constant.InternalServerErrorStatus
+		keepAlive = false
+		responseStr, _ = sv.buildResponseString(res, sv.getMergedHeaders(res, false), true)
+	}
+
+	if _, err := writer.WriteString(responseStr); err != nil {
+		return false, false
+	}
+	if err := writer.Flush(); err != nil {
+		return false, false
+	}
+	return keepAlive, false
 }
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var ErrServerClosed = errors.New("server closed")
+
+const shutdownPollInterval = 50 * time.Millisecond
+
+func (sv *Server) trackListener(listener net.Listener, add bool) bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.listeners == nil {
+		sv.listeners = make(map[net.Listener]struct{})
+	}
+	if add && sv.stopping {
+		return false
+	}
+	if add {
+		sv.listeners[listener] = struct{}{}
+	} else {
+		delete(sv.listeners, listener)
+	}
+	return true
+}
+
+func (sv *Server) isStopping() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	return sv.stopping
+}
+
+// Stops accepting connections, returns false if the server was already stopping
+func (sv *Server) stopListening() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	if sv.stopping {
+		return false
+	}
+	sv.stopping = true
+	for listener := range sv.listeners {
+		listener.Close()
+	}
+	return true
+}
+
+func (sv *Server) closeAllConns() {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	for conn := range sv.conns {
+		conn.Close()
+		delete(sv.conns, conn)
+	}
+}
+
+func (sv *Server) finish(err error) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	select {
+	case <-sv.done:
+		return
+	default:
+	}
+	sv.err = err
+	close(sv.done)
+	fmt.Println("Server stopped")
+}
+
+// Shutdown stops accepting connections, closes idle ones and waits for in-flight requests to finish
+// If the context ends first remaining connections are closed and the context error is returned
+// Hijacked connections like websockets are not waited for
+func (sv *Server) Shutdown(ctx context.Context) error {
+	if !sv.stopListening() {
+		<-sv.done
+		return ErrServerClosed
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Close forced the connections closed while waiting
+		select {
+		case <-sv.done:
+			return ErrServerClosed
+		default:
+		}
+
+		// Connections become idle when their current request finishes
+		if sv.closeIdleConns() {
+			sv.finish(nil)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			sv.closeAllConns()
+			sv.finish(ctx.Err())
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops accepting connections and closes every connection immediately without waiting for requests to finish
+// A running Shutdown is cut short and returns ErrServerClosed, closing a stopped server returns ErrServerClosed
+func (sv *Server) Close() error {
+	if !sv.stopListening() {
+		select {
+		case <-sv.done:
+			return ErrServerClosed
+		default:
+		}
+	}
+
+	sv.closeAllConns()
+	sv.finish(ErrServerClosed)
+	return nil
+}
+
+// Wait blocks until the server is stopped, it returns nil after a graceful shutdown,
+// the context error if the shutdown deadline was reached or ErrServerClosed if the server was closed
+func (sv *Server) Wait() error {
+	<-sv.done
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.err
+}
+
+// Done returns a channel that is closed when the server is stopped
+func (sv *Server) Done() <-chan struct{} {
+	return sv.done
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+)
+
+// Writes a response to the connection while the handler is running
+// Body is sent with chunked encoding unless the handler sets a Content-Length, HTTP/1.0 clients get the body until the connection closes
+type responseStream struct {
+	server        *Server
+	conn          net.Conn
+	reader        *bufio.Reader
+	writer        *bufio.Writer
+	body          *request.Body
+	closeNotify   chan struct{}
+	protocol      string
+	discard       bool
+	keepAlive     bool
+	hijacked      bool
+	chunked       bool
+	contentLength int64
+	written       int64
+}
+
+func (stream *responseStream) WriteHead(res *response.Response) error {
+	headers := stream.server.getMergedHeaders(res, stream.keepAlive)
+	headers.Del(constant.ContentLengthHeader.String())
+
+	stream.contentLength = -1
+	if res.Headers.Has(constant.ContentLengthHeader.String()) {
+		contentLength := res.Headers.Get(constant.ContentLengthHeader.String())
+		length, err := strconv.ParseInt(contentLength, 10, 64)
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid content length %q", contentLength)
+		}
+		stream.contentLength = length
+		headers.Set(constant.ContentLengthHeader.String(), contentLength)
+	}
+
+	// Nothing is framed for statuses that can not have a body, writes are dropped like the body of a HEAD response
+	if !res.StatusCode.AllowsBody() {
+		stream.discard = true
+		if res.StatusCode < constant.OkStatus || res.StatusCode == constant.NoContentStatus {
+			headers.Del(constant.ContentLengthHeader.String())
+		}
+	} else if stream.contentLength < 0 {
+		if stream.protocol == constant.HTTPVersion {
+			stream.chunked = true
+			headers.Set(constant.TransferEncodingHeader.String(), "chunked")
+		} else {
+			stream.keepAlive = false
+		}
+	}
+
+	// Declare trailers known at this point so clients can expect them
+	if stream.chunked && len(res.Trailers) > 0 {
+		names := make([]string, 0, len(res.Trailers))
+		for name := range res.Trailers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		headers.Set(constant.TrailerHeader.String(), strings.Join(names, ", "))
+	}
+
+	if !stream.keepAlive || headers.HasToken(constant.ConnectionHeader.String(), "close") {
+		stream.keepAlive = false
+		headers.Set(constant.ConnectionHeader.String(), "close")
+	}
+
+	head, err := stream.server.buildResponseHead(res, headers)
+	if err != nil {
+		stream.keepAlive = false
+		return err
+	}
+	_, err = stream.writer.WriteString(head)
+	return err
+}
+
+func (stream *responseStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Body of a HEAD response or of a status that can not have one is discarded
+	if stream.discard {
+		stream.written += int64(len(p))
+		return len(p), nil
+	}
+
+	if stream.chunked {
+		if _, err := fmt.Fprintf(stream.writer, "%x\r\n", len(p)); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := stream.writer.Write(p)
+	stream.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	if stream.chunked {
+		if _, err := stream.writer.WriteString("\r\n"); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (stream *responseStream) Flush() error {
+	return stream.writer.Flush()
+}
+
+// Watches the connection in the background, a read only returns when the client closes the connection or sends more data
+// Reader can not be shared with the watcher so the rest of the request body is kept in memory first and the connection is not reused
+func (stream *responseStream) CloseNotify() <-chan struct{} {
+	if stream.closeNotify != nil {
+		return stream.closeNotify
+	}
+
+	stream.closeNotify = make(chan struct{})
+	stream.keepAlive = false
+
+	// Handler can still read the kept body, a body that fails to read keeps its error and is not read again
+	if stream.body != nil {
+		stream.body.Bytes()
+	}
+
+	go func() {
+		// Anything the client sends after the request is not read as a request anymore
+		for {
+			if _, err := stream.reader.Peek(1); err != nil {
+				close(stream.closeNotify)
+				return
+			}
+			stream.reader.Discard(stream.reader.Buffered())
+		}
+	}()
+	return stream.closeNotify
+}
+
+func (stream *responseStream) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if stream.hijacked {
+		return nil, nil, errors.New("connection is already hijacked")
+	}
+	if err := stream.writer.Flush(); err != nil {
+		return nil, nil, err
+	}
+
+	stream.hijacked = true
+	stream.keepAlive = false
+	stream.server.trackConn(stream.conn, false)
+	// Hijacked connections manage their own deadlines
+	stream.conn.SetDeadline(time.Time{})
+	return stream.conn, bufio.NewReadWriter(stream.reader, stream.writer), nil
+}
+
+// Ends the body with the last chunk and trailers
+func (stream *responseStream) finish(res *response.Response) error {
+	if stream.discard {
+		return stream.writer.Flush()
+	}
+
+	if stream.chunked {
+		if err := res.Trailers.Validate(); err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(res.Trailers))
+		for name := range res.Trailers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		trailers := "0\r\n"
+		for _, name := range names {
+			for _, value := range res.Trailers[name] {
+				trailers += fmt.Sprintf("%s: %s\r\n", name, value)
+			}
+		}
+		trailers += "\r\n"
+
+		if _, err := stream.writer.WriteString(trailers); err != nil {
+			return err
+		}
+	}
+
+	// Client can not find the end of the body if the handler wrote less or more than it declared
+	if !stream.chunked && stream.contentLength != stream.written {
+		stream.keepAlive = false
+	}
+
+	return stream.writer.Flush()
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cccaaannn/gohst/src/url"
+)
+
+var ErrUnknownRoute = errors.New("unknown route name")
+
+func (sv *Server) nameRoute(name string, named *handler) {
+	if sv.namedRoutes == nil {
+		sv.namedRoutes = make(map[string]*handler)
+	}
+	if _, ok := sv.namedRoutes[name]; ok {
+		panic(fmt.Sprintf("Route with name of %s is already added\n", name))
+	}
+	sv.namedRoutes[name] = named
+}
+
+// URL builds the path of the named route with the params filled in and escaped, query is appended if it is not empty
+// Missing params and params that do not match their constraint return an error
+func (sv *Server) URL(name string, params map[string]string, query url.Query) (string, error) {
+	handler, ok := sv.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRoute, name)
+	}
+
+	path, err := handler.path.Build(params)
+	if err != nil {
+		return "", err
+	}
+
+	if encoded := query.Encode(); encoded != "" {
+		path += "?" + encoded
+	}
+	return path, nil
+}
@@ -0,0 +1,69 @@
+package url
+
+import (
+	neturl "net/url"
+	"strings"
+)
+
+// Dot segments can be percent encoded, "%2e%2e" is the same as ".."
+func dotSegment(segment string) string {
+	if len(segment) > 6 || !strings.ContainsAny(segment, ".%") {
+		return ""
+	}
+	decoded, err := neturl.PathUnescape(segment)
+	if err != nil || (decoded != "." && decoded != "..") {
+		return ""
+	}
+	return decoded
+}
+
+// CleanPath collapses repeated slashes and resolves "." and ".." segments of an escaped path, ".." never goes above the root
+// A trailing slash is kept and added after a final dot segment, paths that do not start with a slash are returned as they are
+func CleanPath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	segments := make([]string, 0)
+	trailingSlash := false
+	for _, segment := range strings.Split(path[1:], "/") {
+		trailingSlash = false
+		switch dotSegment(segment) {
+		case ".":
+			trailingSlash = true
+		case "..":
+			trailingSlash = true
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+		default:
+			if segment == "" {
+				trailingSlash = true
+				continue
+			}
+			segments = append(segments, segment)
+		}
+	}
+
+	cleaned := "/" + strings.Join(segments, "/")
+	if trailingSlash && len(segments) > 0 {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// HasEncodedDotSegment tells whether a segment of an escaped path decodes to dot segments, like "..%2F..%2Fetc"
+// CleanPath only resolves whole segments so these would reach the params as "../../etc"
+func HasEncodedDotSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if !strings.Contains(segment, "%") || dotSegment(segment) != "" {
+			continue
+		}
+		for _, part := range strings.Split(DecodeParam(segment), "/") {
+			if part == "." || part == ".." {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package url
+
+import "testing"
+
+func TestCleanPath(t *testing.T) {
+	cases := map[string]string{
+		"/":                 "/",
+		"/users":            "/users",
+		"/users/":           "/users/",
+		"//users///5":       "/users/5",
+		"/users/./5":        "/users/5",
+		"/users/5/..":       "/users/",
+		"/a/b/../../../c":   "/c",
+		"/files/%2e%2E/etc": "/etc",
+		"/files/my%20doc":   "/files/my%20doc",
+		"/files/a%2Fb/../c": "/files/c",
+		"/..":               "/",
+		"*":                 "*",
+	}
+
+	for path, expected := range cases {
+		// When
+		cleaned := CleanPath(path)
+
+		// Then
+		if cleaned != expected {
+			t.Fatalf("Expected %v to be cleaned to %v, got %v", path, expected, cleaned)
+		}
+	}
+}
+
+func TestHasEncodedDotSegment(t *testing.T) {
+	cases := map[string]bool{
+		"/files/a%2Fb":                   false,
+		"/files/%2e%2e/etc":              false,
+		"/files/my%20doc":                false,
+		"/files/..%2F..%2Fetc":           true,
+		"/static/..%2F..%2Fetc%2Fpasswd": true,
+		"/files/a%2F.%2Fb":               true,
+		"/files/a%2F%2e%2e":              true,
+		"/files/..../etc":                false,
+	}
+
+	for path, expected := range cases {
+		// When
+		found := HasEncodedDotSegment(path)
+
+		// Then
+		if found != expected {
+			t.Fatalf("Expected %v for %v, got %v", expected, path, found)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package url
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Constraint limits the values a param segment matches, ":id<int>" uses a named constraint and ":id{[0-9]+}" a regular expression
+type constraint struct {
+	// Constraint as it is written in the pattern, params with the same name and text share a node
+	text  string
+	match func(string) bool
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i] | 0x20
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var namedConstraints = map[string]func(string) bool{
+	"int": func(value string) bool {
+		return isDigits(strings.TrimPrefix(value, "-"))
+	},
+	"uint":  isDigits,
+	"alpha": isAlpha,
+	"alnum": func(value string) bool {
+		if value == "" {
+			return false
+		}
+		for i := 0; i < len(value); i++ {
+			if !isDigits(value[i:i+1]) && !isAlpha(value[i:i+1]) {
+				return false
+			}
+		}
+		return true
+	},
+	"uuid": uuidRegexp.MatchString,
+}
+
+// Parses the constraint part of a param segment, "<int>" or "{[0-9]+}"
+func parseConstraint(text string) *constraint {
+	switch {
+	case strings.HasPrefix(text, "<") && strings.HasSuffix(text, ">"):
+		match, ok := namedConstraints[text[1:len(text)-1]]
+		if !ok {
+			panic(fmt.Sprintf("Unknown param constraint %s\n", text))
+		}
+		return &constraint{text: text, match: match}
+	case strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}"):
+		expression, err := regexp.Compile("^(?:" + text[1:len(text)-1] + ")$")
+		if err != nil {
+			panic(fmt.Sprintf("Invalid param constraint %s, %v\n", text, err))
+		}
+		return &constraint{text: text, match: expression.MatchString}
+	default:
+		panic(fmt.Sprintf("Invalid param constraint %s\n", text))
+	}
+}
+
+func (c *constraint) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.text
+}
+
+// Segments are matched escaped so an encoded slash stays in its segment, the constraint checks the decoded value handlers receive
+func (c *constraint) matchSegment(segment string) bool {
+	return c.match(DecodeParam(segment))
+}
@@ -1,6 +1,16 @@
 package url
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strings"
+)
+
+var (
+	ErrMissingParam = errors.New("missing path param")
+	ErrInvalidParam = errors.New("invalid path param")
+)
 
 type segmentType string
 
@@ -13,6 +23,8 @@ const (
 type segment struct {
 	value       string
 	segmentType segmentType
+	constraint  *constraint
+	optional    bool
 }
 
 type Path struct {
@@ -20,19 +32,39 @@ type Path struct {
 	segments []segment
 }
 
+// Param segments are in the form of ":name", ":name<int>" or ":name{regexp}" and can end with "?" to be optional
+func parseParamSegment(part string) segment {
+	seg := segment{segmentType: param}
+
+	// Question mark inside a regexp is part of the regexp
+	if strings.HasSuffix(part, "?") {
+		seg.optional = true
+		part = part[:len(part)-1]
+	}
+
+	end := strings.IndexAny(part, "<{")
+	if end < 0 {
+		seg.value = part
+	} else {
+		seg.value = part[:end]
+		seg.constraint = parseConstraint(part[end:])
+	}
+	return seg
+}
+
 func parsePathSegments(pattern string) []segment {
 	segments := make([]segment, 0)
 	parts := strings.Split(pattern, "/")
 	for _, part := range parts {
-		segmentType := static
-		if strings.HasPrefix(part, ":") {
-			segmentType = param
-			part = part[1:]
+		switch {
+		case strings.HasPrefix(part, ":"):
+			segments = append(segments, parseParamSegment(part[1:]))
+		case strings.HasPrefix(part, "*"):
+			// Named wildcards capture the rest of the path, "*" alone does not capture
+			segments = append(segments, segment{value: part[1:], segmentType: wildcard})
+		default:
+			segments = append(segments, segment{value: part, segmentType: static})
 		}
-		if part == "*" {
-			segmentType = wildcard
-		}
-		segments = append(segments, segment{value: part, segmentType: segmentType})
 	}
 	return segments
 }
@@ -53,6 +85,55 @@ func CreatePath(pattern string) Path {
 	return Path{pattern: pattern, segments: segments}
 }
 
+func (path Path) String() string {
+	return path.pattern
+}
+
+// Expand returns a path for every combination of the optional segments, "/users/:id?" expands to "/users" and "/users/:id"
+// Optional segments can only be at the end of the pattern
+func (path Path) Expand() []Path {
+	first := len(path.segments)
+	for i, segment := range path.segments {
+		if segment.optional && first == len(path.segments) {
+			first = i
+		}
+		if !segment.optional && first < i {
+			panic(fmt.Sprintf("Optional segments must be at the end of the pattern %s\n", path.pattern))
+		}
+	}
+	if first == len(path.segments) {
+		return []Path{path}
+	}
+
+	parts := strings.Split(path.pattern, "/")
+	paths := make([]Path, 0, len(parts)-first+1)
+	for end := first; end <= len(parts); end++ {
+		variant := make([]string, 0, end)
+		for i, part := range parts[:end] {
+			if path.segments[i].optional {
+				part = strings.TrimSuffix(part, "?")
+			}
+			variant = append(variant, part)
+		}
+
+		pattern := strings.Join(variant, "/")
+		if pattern == "" {
+			pattern = "/"
+		}
+		paths = append(paths, CreatePath(pattern))
+	}
+	return paths
+}
+
+// DecodeParam decodes the escaped value of a param, values that are not valid escapes are returned as they are
+func DecodeParam(value string) string {
+	decoded, err := neturl.PathUnescape(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
 func (path Path) Match(text string) (map[string]string, bool) {
 	textSegments := strings.Split(text, "/")
 	params := make(map[string]string)
@@ -71,6 +152,9 @@ func (path Path) Match(text string) (map[string]string, bool) {
 	for i, segment := range path.segments {
 		textSegment := textSegments[i]
 		if segment.segmentType == param {
+			if segment.constraint != nil && !segment.constraint.matchSegment(textSegment) {
+				return nil, false
+			}
 			params[segment.value] = textSegment
 			continue
 		}
@@ -78,8 +162,57 @@ func (path Path) Match(text string) (map[string]string, bool) {
 			return nil, false
 		}
 		if segment.segmentType == wildcard {
+			if segment.value != "" {
+				params[segment.value] = strings.Join(textSegments[i:], "/")
+			}
 			return params, true
 		}
 	}
 	return params, true
 }
+
+// Build fills the params of the pattern with the given values and escapes them, optional params can be left out
+// Constraints are checked against the values as they are given, the same values handlers receive after routing
+// Values of a named wildcard can contain slashes, every part between them is escaped on its own
+func (path Path) Build(params map[string]string) (string, error) {
+	parts := make([]string, 0, len(path.segments))
+
+	for _, segment := range path.segments {
+		switch segment.segmentType {
+		case static:
+			parts = append(parts, segment.value)
+		case param:
+			value, ok := params[segment.value]
+			if !ok && segment.optional {
+				// Optional segments are at the end, the ones after this can not be set either
+				return joinPath(parts), nil
+			}
+			if !ok || value == "" {
+				return "", fmt.Errorf("%w: %s", ErrMissingParam, segment.value)
+			}
+			if segment.constraint != nil && !segment.constraint.match(value) {
+				return "", fmt.Errorf("%w: %s does not match %s", ErrInvalidParam, segment.value, segment.constraint.text)
+			}
+			parts = append(parts, neturl.PathEscape(value))
+		case wildcard:
+			// Unnamed wildcards have nothing to fill, named ones can be empty but must be given
+			value, ok := params[segment.value]
+			if !ok && segment.value != "" {
+				return "", fmt.Errorf("%w: %s", ErrMissingParam, segment.value)
+			}
+			for _, part := range strings.Split(value, "/") {
+				parts = append(parts, neturl.PathEscape(part))
+			}
+		}
+	}
+
+	return joinPath(parts), nil
+}
+
+func joinPath(parts []string) string {
+	path := strings.Join(parts, "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -1,25 +1,143 @@
 package url
 
-import "strings"
-
-func ParseQuery(query string) map[string]string {
-	params := make(map[string]string)
-	parts := strings.Split(query, "&")
-	for _, part := range parts {
-		split := strings.Split(part, "=")
-		if len(split) == 2 {
-			params[split[0]] = split[1]
-		} else {
-			params[split[0]] = ""
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMissingQuery = errors.New("missing query parameter")
+
+// Query maps decoded query keys to their values in the order they appear, "?tag=a&tag=b" has two values for "tag"
+type Query map[string][]string
+
+// ParseQuery decodes a query string, "+" is a space and percent escapes are decoded
+// Pairs without a key or with invalid escapes are skipped
+func ParseQuery(query string) Query {
+	params := make(Query)
+	for _, part := range strings.Split(query, "&") {
+		if part == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(part, "=")
+		key, err := neturl.QueryUnescape(key)
+		if err != nil || key == "" {
+			continue
 		}
+		value, err = neturl.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		params[key] = append(params[key], value)
 	}
 	return params
 }
 
+// SplitQuery splits a request target at the first "?", the query can contain more of them
 func SplitQuery(path string) (string, string) {
-	parts := strings.Split(path, "?")
-	if len(parts) == 1 {
-		return parts[0], ""
+	path, query, _ := strings.Cut(path, "?")
+	return path, query
+}
+
+// Encode returns the query in form encoding with the keys sorted, values of a key keep their order
+func (query Query) Encode() string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var encoded strings.Builder
+	for _, key := range keys {
+		values := query[key]
+		if len(values) == 0 {
+			values = []string{""}
+		}
+		for _, value := range values {
+			if encoded.Len() > 0 {
+				encoded.WriteByte('&')
+			}
+			encoded.WriteString(neturl.QueryEscape(key))
+			encoded.WriteByte('=')
+			encoded.WriteString(neturl.QueryEscape(value))
+		}
+	}
+	return encoded.String()
+}
+
+// Get returns the first value of the key, empty if it is not set
+func (query Query) Get(key string) string {
+	values := query[key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// GetAll returns every value of the key
+func (query Query) GetAll(key string) []string {
+	return query[key]
+}
+
+// Has reports whether the key is in the query, even without a value as in "?debug"
+func (query Query) Has(key string) bool {
+	_, ok := query[key]
+	return ok
+}
+
+func (query Query) first(key string) (string, error) {
+	values, ok := query[key]
+	if !ok || len(values) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingQuery, key)
+	}
+	return values[0], nil
+}
+
+func (query Query) GetInt(key string) (int, error) {
+	value, err := query.first(key)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("query parameter %s: %w", key, err)
+	}
+	return number, nil
+}
+
+// GetBool parses the first value of the key, a key without a value as in "?debug" is true
+func (query Query) GetBool(key string) (bool, error) {
+	value, err := query.first(key)
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return true, nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("query parameter %s: %w", key, err)
+	}
+	return flag, nil
+}
+
+// GetTime parses the first value of the key with the layout, RFC 3339 is used if the layout is empty
+func (query Query) GetTime(key string, layout string) (time.Time, error) {
+	value, err := query.first(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	parsed, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query parameter %s: %w", key, err)
 	}
-	return parts[0], parts[1]
+	return parsed, nil
 }
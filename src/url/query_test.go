@@ -0,0 +1,51 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	// Given
+	text := "q=hello%20world+again&tag=a&tag=b&expr=a%3Db=c&debug&limit=20&since=2024-01-02T03:04:05Z&&=empty&bad=%zz"
+
+	// When
+	query := ParseQuery(text)
+
+	// Then
+	if query.Get("q") != "hello world again" {
+		t.Fatalf("Expected decoded value, got %v", query.Get("q"))
+	}
+	if fmt.Sprint(query.GetAll("tag")) != "[a b]" {
+		t.Fatalf("Expected both tag values, got %v", query.GetAll("tag"))
+	}
+	if query.Get("expr") != "a=b=c" {
+		t.Fatalf("Expected value with equal signs, got %v", query.Get("expr"))
+	}
+	if query.Has("") || query.Has("bad") || len(query) != 6 {
+		t.Fatalf("Expected empty keys and invalid escapes to be skipped, got %v", query)
+	}
+
+	if debug, err := query.GetBool("debug"); err != nil || !debug {
+		t.Fatalf("Expected key without value to be true, got %v %v", debug, err)
+	}
+	if limit, err := query.GetInt("limit"); err != nil || limit != 20 {
+		t.Fatalf("Expected limit 20, got %v %v", limit, err)
+	}
+	if _, err := query.GetInt("q"); err == nil {
+		t.Fatalf("Expected error for a value that is not a number")
+	}
+	if _, err := query.GetInt("offset"); !errors.Is(err, ErrMissingQuery) {
+		t.Fatalf("Expected missing query error, got %v", err)
+	}
+	expectedTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if since, err := query.GetTime("since", ""); err != nil || !since.Equal(expectedTime) {
+		t.Fatalf("Expected time %v, got %v %v", expectedTime, since, err)
+	}
+
+	if len(ParseQuery("")) != 0 {
+		t.Fatalf("Expected empty query to have no keys")
+	}
+}
@@ -0,0 +1,224 @@
+package url
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Part of a pattern the tree matches at once, static text can span multiple segments
+type token struct {
+	value       string
+	segmentType segmentType
+	constraint  *constraint
+}
+
+// Joins consecutive static segments, "/users/:id/posts" becomes "/users/", ":id", "/posts"
+func (path Path) tokens() []token {
+	tokens := make([]token, 0)
+	text := ""
+
+	for i, segment := range path.segments {
+		if i > 0 {
+			text += "/"
+		}
+		if segment.segmentType == static {
+			text += segment.value
+			continue
+		}
+
+		if segment.segmentType == wildcard && i != len(path.segments)-1 {
+			panic(fmt.Sprintf("Wildcard must be the last segment of the pattern %s\n", path.pattern))
+		}
+
+		if text != "" {
+			tokens = append(tokens, token{value: text, segmentType: static})
+			text = ""
+		}
+		tokens = append(tokens, token{value: segment.value, segmentType: segment.segmentType, constraint: segment.constraint})
+	}
+
+	if text != "" {
+		tokens = append(tokens, token{value: text, segmentType: static})
+	}
+	return tokens
+}
+
+type node[T any] struct {
+	// Static text for static nodes, parameter name for param and wildcard nodes
+	value       string
+	segmentType segmentType
+	constraint  *constraint
+
+	// Static children are indexed by their first byte
+	indices   []byte
+	statics   []*node[T]
+	params    []*node[T]
+	wildcards []*node[T]
+
+	hasValue bool
+	leaf     T
+}
+
+// Tree is a compressed prefix tree of path patterns
+// Lookup prefers static children, then constrained params, then other params and then wildcards so the result does not depend on registration order
+type Tree[T any] struct {
+	root *node[T]
+}
+
+func CreateTree[T any]() *Tree[T] {
+	return &Tree[T]{root: &node[T]{segmentType: static}}
+}
+
+func commonPrefixLength(a string, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func (n *node[T]) insertStatic(text string) *node[T] {
+	for {
+		i := strings.IndexByte(string(n.indices), text[0])
+		if i < 0 {
+			child := &node[T]{value: text, segmentType: static}
+			n.indices = append(n.indices, text[0])
+			n.statics = append(n.statics, child)
+			return child
+		}
+
+		child := n.statics[i]
+		length := commonPrefixLength(child.value, text)
+
+		// Split the child so the shared prefix becomes its own node
+		if length < len(child.value) {
+			split := &node[T]{
+				value:       child.value[:length],
+				segmentType: static,
+				indices:     []byte{child.value[length]},
+				statics:     []*node[T]{child},
+			}
+			child.value = child.value[length:]
+			n.statics[i] = split
+			child = split
+		}
+
+		if length == len(text) {
+			return child
+		}
+		n = child
+		text = text[length:]
+	}
+}
+
+func (n *node[T]) insertDynamic(tok token) *node[T] {
+	children := &n.params
+	if tok.segmentType == wildcard {
+		children = &n.wildcards
+	}
+
+	for _, child := range *children {
+		if child.value == tok.value && child.constraint.String() == tok.constraint.String() {
+			return child
+		}
+	}
+
+	child := &node[T]{value: tok.value, segmentType: tok.segmentType, constraint: tok.constraint}
+	*children = append(*children, child)
+
+	// Constrained params are tried before the ones matching anything
+	slices.SortStableFunc(*children, func(a *node[T], b *node[T]) int {
+		if (a.constraint != nil) == (b.constraint != nil) {
+			return 0
+		}
+		if a.constraint != nil {
+			return -1
+		}
+		return 1
+	})
+	return child
+}
+
+// Insert returns the leaf value of the pattern, it is created with the zero value if the pattern is new
+func (tree *Tree[T]) Insert(path Path) *T {
+	n := tree.root
+	for _, tok := range path.tokens() {
+		if tok.segmentType == static {
+			n = n.insertStatic(tok.value)
+		} else {
+			n = n.insertDynamic(tok)
+		}
+	}
+
+	n.hasValue = true
+	return &n.leaf
+}
+
+// Matches the children of n against the rest of the text, params are appended while descending and removed on backtracking
+func (n *node[T]) lookup(text string, params *[]Param, accept func(T) bool) (*node[T], bool) {
+	if text == "" && n.hasValue && accept(n.leaf) {
+		return n, true
+	}
+
+	if text != "" {
+		for i, index := range n.indices {
+			if index != text[0] {
+				continue
+			}
+			child := n.statics[i]
+			if strings.HasPrefix(text, child.value) {
+				if found, ok := child.lookup(text[len(child.value):], params, accept); ok {
+					return found, true
+				}
+			}
+			break
+		}
+
+		end := strings.IndexByte(text, '/')
+		if end < 0 {
+			end = len(text)
+		}
+		if end > 0 {
+			for _, child := range n.params {
+				if child.constraint != nil && !child.constraint.matchSegment(text[:end]) {
+					continue
+				}
+				*params = append(*params, Param{Key: child.value, Value: text[:end]})
+				if found, ok := child.lookup(text[end:], params, accept); ok {
+					return found, true
+				}
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+
+	// Wildcards take the rest of the text, even if it is empty
+	for _, child := range n.wildcards {
+		if child.hasValue && accept(child.leaf) {
+			// Unnamed wildcards do not capture
+			if child.value != "" {
+				*params = append(*params, Param{Key: child.value, Value: text})
+			}
+			return child, true
+		}
+	}
+
+	return nil, false
+}
+
+// Lookup finds the leaf matching the text with the highest priority that accept returns true for
+// Accept is called for every matching leaf in priority order until it returns true, params are appended to the given slice
+func (tree *Tree[T]) Lookup(text string, params []Param, accept func(T) bool) (T, []Param, bool) {
+	found, ok := tree.root.lookup(text, &params, accept)
+	if !ok {
+		var zero T
+		return zero, params, false
+	}
+	return found.leaf, params, true
+}
@@ -0,0 +1,192 @@
+package url
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const benchmarkRouteCount = 300
+
+func createBenchmarkPatterns() []string {
+	patterns := make([]string, 0, benchmarkRouteCount)
+	for i := 0; i < benchmarkRouteCount/3; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("/api/v1/resource%d", i),
+			fmt.Sprintf("/api/v1/resource%d/:id", i),
+			fmt.Sprintf("/api/v1/resource%d/:id/items/:item", i),
+		)
+	}
+	return patterns
+}
+
+func TestTreePriority(t *testing.T) {
+	// Given
+	tree := CreateTree[string]()
+	for _, pattern := range []string{"/*", "/users/:id", "/users/me", "/users/:id/posts", "/files/*", "/static/*filepath"} {
+		*tree.Insert(CreatePath(pattern)) = pattern
+	}
+
+	cases := []struct {
+		text    string
+		pattern string
+		params  []Param
+	}{
+		{text: "/users/me", pattern: "/users/me", params: []Param{}},
+		{text: "/users/5", pattern: "/users/:id", params: []Param{{Key: "id", Value: "5"}}},
+		{text: "/users/me/posts", pattern: "/users/:id/posts", params: []Param{{Key: "id", Value: "me"}}},
+		{text: "/users/5/other", pattern: "/*", params: []Param{}},
+		{text: "/files/a/b.txt", pattern: "/files/*", params: []Param{}},
+		{text: "/static/css/site.css", pattern: "/static/*filepath", params: []Param{{Key: "filepath", Value: "css/site.css"}}},
+		{text: "/static/", pattern: "/static/*filepath", params: []Param{{Key: "filepath", Value: ""}}},
+		{text: "/", pattern: "/*", params: []Param{}},
+	}
+
+	for _, c := range cases {
+		// When
+		pattern, params, ok := tree.Lookup(c.text, make([]Param, 0), func(string) bool { return true })
+
+		// Then
+		if !ok || pattern != c.pattern {
+			t.Fatalf("Expected %v to match %v, got %v", c.text, c.pattern, pattern)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(c.params) {
+			t.Fatalf("Expected params %v for %v, got %v", c.params, c.text, params)
+		}
+	}
+}
+
+func TestTreeConstraints(t *testing.T) {
+	// Given
+	tree := CreateTree[string]()
+	patterns := []string{"/users/:name", "/users/:id<int>", "/posts/:slug{[a-z-]+}", "/items/:id<uuid>", "/archive/:year<uint>/:month?", "/files/:name{[^0-9]+}"}
+	for _, pattern := range patterns {
+		for _, path := range CreatePath(pattern).Expand() {
+			*tree.Insert(path) = pattern
+		}
+	}
+
+	cases := []struct {
+		text    string
+		pattern string
+		params  []Param
+		ok      bool
+	}{
+		{text: "/users/42", pattern: "/users/:id<int>", params: []Param{{Key: "id", Value: "42"}}, ok: true},
+		{text: "/users/bob", pattern: "/users/:name", params: []Param{{Key: "name", Value: "bob"}}, ok: true},
+		{text: "/posts/hello-world", pattern: "/posts/:slug{[a-z-]+}", params: []Param{{Key: "slug", Value: "hello-world"}}, ok: true},
+		{text: "/posts/Hello", ok: false},
+		{text: "/items/123e4567-e89b-12d3-a456-426614174000", pattern: "/items/:id<uuid>", params: []Param{{Key: "id", Value: "123e4567-e89b-12d3-a456-426614174000"}}, ok: true},
+		{text: "/items/123", ok: false},
+		{text: "/archive/2024", pattern: "/archive/:year<uint>/:month?", params: []Param{{Key: "year", Value: "2024"}}, ok: true},
+		{text: "/archive/2024/05", pattern: "/archive/:year<uint>/:month?", params: []Param{{Key: "year", Value: "2024"}, {Key: "month", Value: "05"}}, ok: true},
+		{text: "/archive/last", ok: false},
+		{text: "/files/my%20doc", pattern: "/files/:name{[^0-9]+}", params: []Param{{Key: "name", Value: "my%20doc"}}, ok: true},
+		{text: "/files/%31", ok: false},
+		{text: "/users/%34%32", pattern: "/users/:id<int>", params: []Param{{Key: "id", Value: "%34%32"}}, ok: true},
+	}
+
+	for _, c := range cases {
+		// When
+		pattern, params, ok := tree.Lookup(c.text, make([]Param, 0), func(string) bool { return true })
+
+		// Then
+		if ok != c.ok || pattern != c.pattern {
+			t.Fatalf("Expected %v to match %v (%v), got %v (%v)", c.text, c.pattern, c.ok, pattern, ok)
+		}
+		if ok && fmt.Sprint(params) != fmt.Sprint(c.params) {
+			t.Fatalf("Expected params %v for %v, got %v", c.params, c.text, params)
+		}
+	}
+}
+
+func TestPathExpand(t *testing.T) {
+	cases := map[string]string{
+		"/users":          "[/users]",
+		"/users/:id?":     "[/users /users/:id]",
+		"/:lang?":         "[/ /:lang]",
+		"/a/:b<int>?/:c?": "[/a /a/:b<int> /a/:b<int>/:c]",
+	}
+
+	for pattern, expected := range cases {
+		// When
+		paths := CreatePath(pattern).Expand()
+
+		// Then
+		if fmt.Sprint(paths) != expected {
+			t.Fatalf("Expected %v to expand to %v, got %v", pattern, expected, paths)
+		}
+	}
+}
+
+func TestPathBuild(t *testing.T) {
+	cases := []struct {
+		pattern string
+		params  map[string]string
+		path    string
+		err     error
+	}{
+		{pattern: "/users/:id<int>", params: map[string]string{"id": "5"}, path: "/users/5"},
+		{pattern: "/users/:id<int>", params: map[string]string{"id": "me"}, err: ErrInvalidParam},
+		{pattern: "/users/:id/posts", params: map[string]string{}, err: ErrMissingParam},
+		{pattern: "/files/:name", params: map[string]string{"name": "my doc/1?"}, path: "/files/my%20doc%2F1%3F"},
+		{pattern: "/static/*filepath", params: map[string]string{"filepath": "css/my site.css"}, path: "/static/css/my%20site.css"},
+		{pattern: "/static/*filepath", params: map[string]string{}, err: ErrMissingParam},
+		{pattern: "/static/*filepath", params: map[string]string{"filepath": ""}, path: "/static/"},
+		{pattern: "/static/*", params: nil, path: "/static/"},
+		{pattern: "/files/:name{[^0-9]+}", params: map[string]string{"name": "my doc"}, path: "/files/my%20doc"},
+		{pattern: "/archive/:year/:month?", params: map[string]string{"year": "2024"}, path: "/archive/2024"},
+		{pattern: "/", params: nil, path: "/"},
+	}
+
+	for _, c := range cases {
+		// When
+		path, err := CreatePath(c.pattern).Build(c.params)
+
+		// Then
+		if !errors.Is(err, c.err) || path != c.path {
+			t.Fatalf("Expected %v to build %v (%v), got %v (%v)", c.pattern, c.path, c.err, path, err)
+		}
+	}
+}
+
+func BenchmarkTreeLookup(b *testing.B) {
+	tree := CreateTree[string]()
+	for _, pattern := range createBenchmarkPatterns() {
+		*tree.Insert(CreatePath(pattern)) = pattern
+	}
+	params := make([]Param, 0, 4)
+	accept := func(string) bool { return true }
+	text := fmt.Sprintf("/api/v1/resource%d/42/items/7", benchmarkRouteCount/3-1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, ok := tree.Lookup(text, params[:0], accept); !ok {
+			b.Fatalf("Expected %v to match", text)
+		}
+	}
+}
+
+func BenchmarkLinearMatch(b *testing.B) {
+	paths := make([]Path, 0, benchmarkRouteCount)
+	for _, pattern := range createBenchmarkPatterns() {
+		paths = append(paths, CreatePath(pattern))
+	}
+	text := fmt.Sprintf("/api/v1/resource%d/42/items/7", benchmarkRouteCount/3-1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		matched := false
+		for _, path := range paths {
+			if _, ok := path.Match(text); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.Fatalf("Expected %v to match", text)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule checks a set field value, it returns the message of the error when the value fails
+type rule struct {
+	name  string
+	check func(value reflect.Value) (string, bool)
+}
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasLength(kind reflect.Kind) bool {
+	return kind == reflect.String || kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+func number(value reflect.Value) float64 {
+	switch {
+	case value.CanInt():
+		return float64(value.Int())
+	case value.CanUint():
+		return float64(value.Uint())
+	default:
+		return value.Float()
+	}
+}
+
+// Strings are measured in characters, collections in items
+func length(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return utf8.RuneCountInString(value.String())
+	}
+	return value.Len()
+}
+
+func lengthUnit(kind reflect.Kind) string {
+	if kind == reflect.String {
+		return "characters"
+	}
+	return "items"
+}
+
+// Text form of a value compared by oneof
+func text(value reflect.Value) string {
+	switch {
+	case value.Kind() == reflect.String:
+		return value.String()
+	case value.CanInt():
+		return strconv.FormatInt(value.Int(), 10)
+	case value.CanUint():
+		return strconv.FormatUint(value.Uint(), 10)
+	default:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	}
+}
+
+func createRule(name string, param string, fieldType reflect.Type) (rule, error) {
+	kind := fieldType.Kind()
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return rule{}, fmt.Errorf("%s needs a number, got %q", name, param)
+		}
+		bound, compare := "at least", func(n float64) bool { return n >= limit }
+		if name == "max" {
+			bound, compare = "at most", func(n float64) bool { return n <= limit }
+		}
+
+		switch {
+		case isNumber(kind):
+			message := fmt.Sprintf("must be %s %s", bound, param)
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				return message, compare(number(value))
+			}}, nil
+		case hasLength(kind):
+			message := fmt.Sprintf("must have %s %s %s", bound, param, lengthUnit(kind))
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				return message, compare(float64(length(value)))
+			}}, nil
+		}
+	case "len":
+		expected, err := strconv.Atoi(param)
+		if err != nil {
+			return rule{}, fmt.Errorf("len needs an integer, got %q", param)
+		}
+		if hasLength(kind) {
+			message := fmt.Sprintf("must have exactly %d %s", expected, lengthUnit(kind))
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				return message, length(value) == expected
+			}}, nil
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		if len(options) == 0 {
+			return rule{}, fmt.Errorf("oneof needs values separated by spaces")
+		}
+		if kind == reflect.String || isNumber(kind) {
+			message := "must be one of " + strings.Join(options, ", ")
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				current := text(value)
+				for _, option := range options {
+					if current == option {
+						return "", true
+					}
+				}
+				return message, false
+			}}, nil
+		}
+	case "email":
+		if kind == reflect.String {
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				address, err := mail.ParseAddress(value.String())
+				return "must be a valid email address", err == nil && address.Address == value.String()
+			}}, nil
+		}
+	case "uuid":
+		if kind == reflect.String {
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				return "must be a valid uuid", uuidRegexp.MatchString(value.String())
+			}}, nil
+		}
+	case "regexp":
+		expression, err := regexp.Compile(param)
+		if err != nil {
+			return rule{}, fmt.Errorf("regexp %q does not compile, %v", param, err)
+		}
+		if kind == reflect.String {
+			message := "must match " + param
+			return rule{name: name, check: func(value reflect.Value) (string, bool) {
+				return message, expression.MatchString(value.String())
+			}}, nil
+		}
+	default:
+		return rule{}, fmt.Errorf("unknown rule %q", name)
+	}
+
+	return rule{}, fmt.Errorf("rule %s does not apply to %s", name, fieldType)
+}
@@ -0,0 +1,200 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// FieldError is a field that violates a rule, fields are named like they are in JSON, "items[0].name" for nested fields
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every field that failed validation, it is returned as the error of Validate
+type Errors []FieldError
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Field+" "+err.Message)
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+// Rules of a struct field, parsed once per type
+type field struct {
+	index    int
+	name     string
+	required bool
+	rules    []rule
+}
+
+var fieldCache sync.Map
+
+// Validate checks the fields of a struct against the rules in their "validate" tags, returns Errors if any field fails
+// Rules are separated by commas like `validate:"required,min=3,max=20"`, empty strings and collections and nil pointers are only checked by required
+// Numbers are always checked, zero included, a pointer to a number tells whether it was given
+// Nested structs, pointers and slices of structs are validated too, values that are not structs have nothing to validate
+// Invalid tags panic the first time a type is validated, Prepare panics earlier
+func Validate(v any) error {
+	errs := Errors{}
+	validateValue(reflect.ValueOf(v), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Prepare parses the rules of the type of v and the structs nested in it, so invalid tags panic before the first Validate
+func Prepare(v any) {
+	prepareType(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+// Follows the same pointers, slices and fields as validateValue, seen stops recursive types
+func prepareType(t reflect.Type, seen map[reflect.Type]bool) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+
+	seen[t] = true
+	for _, f := range structFields(t) {
+		prepareType(t.Field(f.index).Type, seen)
+	}
+}
+
+func validateValue(value reflect.Value, path string, errs *Errors) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, f := range structFields(value.Type()) {
+			fieldPath := f.name
+			if path != "" {
+				fieldPath = path + "." + f.name
+			}
+			fieldValue := value.Field(f.index)
+			validateField(f, fieldValue, fieldPath, errs)
+			validateValue(fieldValue, fieldPath, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// Values that were left out, a zero number is a value so it is not empty
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// Pointer fields are set when they are not nil so a pointer to a zero value passes required
+func validateField(f field, value reflect.Value, path string, errs *Errors) {
+	if f.required && value.IsZero() {
+		*errs = append(*errs, FieldError{Field: path, Rule: "required", Message: "is required"})
+		return
+	}
+
+	// Optional fields are only checked when they are given
+	if isEmpty(value) {
+		return
+	}
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+
+	for _, r := range f.rules {
+		if message, ok := r.check(value); !ok {
+			*errs = append(*errs, FieldError{Field: path, Rule: r.name, Message: message})
+		}
+	}
+}
+
+// Exported fields of the type with their rules, fields without rules are kept since they may contain fields with rules
+func structFields(t reflect.Type) []field {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]field)
+	}
+
+	fields := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		f := field{index: i, name: fieldName(structField)}
+		tag := structField.Tag.Get("validate")
+		if tag != "" {
+			f.required, f.rules = parseTag(tag, structField)
+		}
+		fields = append(fields, f)
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// Fields are named by their json name or the name they are bound from so errors match the request
+func fieldName(structField reflect.StructField) string {
+	for _, key := range []string{"json", "path", "query", "header", "cookie"} {
+		name, _, _ := strings.Cut(structField.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return structField.Name
+}
+
+// Regular expressions may contain commas so regexp takes the rest of the tag
+func parseTag(tag string, structField reflect.StructField) (bool, []rule) {
+	fieldType := structField.Type
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	required := false
+	rules := make([]rule, 0)
+	for tag != "" {
+		var text string
+		if strings.HasPrefix(tag, "regexp=") {
+			text, tag = tag, ""
+		} else {
+			text, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(text), "=")
+		if name == "required" {
+			required = true
+			continue
+		}
+
+		r, err := createRule(name, param, fieldType)
+		if err != nil {
+			panic(fmt.Sprintf("Invalid validate tag on field %s, %v\n", structField.Name, err))
+		}
+		rules = append(rules, r)
+	}
+	return required, rules
+}
@@ -0,0 +1,182 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `validate:"len=5,regexp=^[0-9]{3,5}$"`
+}
+
+type user struct {
+	Name    string    `json:"name" validate:"required,min=2,max=5"`
+	Age     int       `json:"age" validate:"min=18,max=130"`
+	Role    string    `json:"role" validate:"oneof=admin user"`
+	Email   string    `json:"email" validate:"email"`
+	Id      string    `json:"id" validate:"uuid"`
+	Tags    []string  `json:"tags" validate:"max=2"`
+	Score   *float64  `json:"score" validate:"required,max=1.5"`
+	Home    address   `json:"home"`
+	Others  []address `json:"others"`
+	ignored string    `validate:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	// Given
+	score := 2.5
+	invalid := user{
+		Name:   "Banana king",
+		Age:    12,
+		Role:   "guest",
+		Email:  "Can <can@example.com>",
+		Id:     "1234",
+		Tags:   []string{"a", "b", "c"},
+		Score:  &score,
+		Home:   address{Zip: "1234"},
+		Others: []address{{City: "Izmir", Zip: "35000"}, {City: "Ankara", Zip: "6a000"}},
+	}
+	valid := user{Name: "Can", Age: 30, Role: "admin", Email: "can@example.com", Id: "7d444840-9dc0-11d1-b245-5ffdce74fad2", Home: address{City: "Izmir"}}
+
+	// When
+	invalidErr := Validate(&invalid)
+	missingErr := Validate(valid)
+	valid.Score = new(float64)
+	validErr := Validate(valid)
+
+	// Then
+	var errs Errors
+	if !errors.As(invalidErr, &errs) {
+		t.Fatalf("Expected validation errors, got %v", invalidErr)
+	}
+	expected := "[{name max must have at most 5 characters} {age min must be at least 18} {role oneof must be one of admin, user} " +
+		"{email email must be a valid email address} {id uuid must be a valid uuid} {tags max must have at most 2 items} " +
+		"{score max must be at most 1.5} {home.city required is required} {home.Zip len must have exactly 5 characters} " +
+		"{others[1].Zip regexp must match ^[0-9]{3,5}$}]"
+	if fmt.Sprint([]FieldError(errs)) != expected {
+		t.Fatalf("Expected %v, got %v", expected, []FieldError(errs))
+	}
+
+	if missingErr == nil || missingErr.Error() != "validation failed: score is required" {
+		t.Fatalf("Expected missing pointer to fail required, got %v", missingErr)
+	}
+	if validErr != nil {
+		t.Fatalf("Expected valid user, got %v", validErr)
+	}
+}
+
+func TestValidateNonStruct(t *testing.T) {
+	// Given
+	values := []any{nil, 5, "text", []int{1, 2}, []address{{City: "Izmir"}}}
+
+	for _, value := range values {
+		// When
+		err := Validate(value)
+
+		// Then
+		if err != nil {
+			t.Fatalf("Expected nothing to validate for %v, got %v", value, err)
+		}
+	}
+}
+
+func TestInvalidTag(t *testing.T) {
+	cases := []any{
+		struct {
+			A string `validate:"min=abc"`
+		}{},
+		struct {
+			A bool `validate:"max=3"`
+		}{},
+		struct {
+			A string `validate:"unknown"`
+		}{},
+		struct {
+			A string `validate:"regexp=[a-"`
+		}{},
+	}
+
+	for _, value := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected panic for %T", value)
+				}
+			}()
+
+			Validate(value)
+		}()
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	type line struct {
+		SKU string `validate:"len=abc"`
+	}
+	cases := []any{
+		struct {
+			A string `validate:"unknown"`
+		}{},
+		&struct {
+			Lines []line
+		}{},
+		struct {
+			Line *line
+		}{},
+	}
+
+	for _, value := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected panic for %T", value)
+				}
+			}()
+
+			Prepare(value)
+		}()
+	}
+
+	// Recursive types and values that are not structs do not panic
+	type node struct {
+		Name     string `validate:"required"`
+		Children []node
+	}
+	Prepare(node{})
+	Prepare(3)
+	Prepare(nil)
+}
+
+type order struct {
+	Quantity int     `validate:"min=1,max=100"`
+	Discount float64 `validate:"max=0.5"`
+	Note     string  `validate:"min=3"`
+	Count    *int    `validate:"min=1"`
+}
+
+func TestValidateZeroValues(t *testing.T) {
+	// Given
+	zero := 0
+	cases := []struct {
+		value    order
+		expected string
+	}{
+		{value: order{}, expected: "validation failed: Quantity must be at least 1"},
+		{value: order{Quantity: -3}, expected: "validation failed: Quantity must be at least 1"},
+		{value: order{Quantity: 1, Discount: 0.75}, expected: "validation failed: Discount must be at most 0.5"},
+		{value: order{Quantity: 1, Count: &zero}, expected: "validation failed: Count must be at least 1"},
+		{value: order{Quantity: 1}, expected: ""},
+	}
+
+	for _, c := range cases {
+		// When
+		err := Validate(c.value)
+
+		// Then
+		if fmt.Sprint(err) != c.expected && !(err == nil && c.expected == "") {
+			t.Fatalf("Expected %q for %+v, got %v", c.expected, c.value, err)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	continuationMessage MessageType = 0
+	TextMessage         MessageType = 1
+	BinaryMessage       MessageType = 2
+	CloseMessage        MessageType = 8
+	PingMessage         MessageType = 9
+	PongMessage         MessageType = 10
+)
+
+func (mt MessageType) isControl() bool {
+	return mt >= CloseMessage
+}
+
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+)
+
+const maxControlPayload = 125
+
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+var ErrClosed = errors.New("websocket: connection closed")
+
+type frame struct {
+	fin     bool
+	opcode  MessageType
+	payload []byte
+}
+
+// Conn is a websocket connection, one goroutine can read while others write
+type Conn struct {
+	conn           net.Conn
+	rw             *bufio.ReadWriter
+	subprotocol    string
+	maxMessageSize int64
+	fragmentSize   int
+	closeTimeout   time.Duration
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	// Called for pings and pongs, pings are answered with a pong when pingHandler is nil
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func createConn(netConn net.Conn, rw *bufio.ReadWriter, subprotocol string, options *Options) *Conn {
+	conn := &Conn{
+		conn:           netConn,
+		rw:             rw,
+		subprotocol:    subprotocol,
+		maxMessageSize: options.MaxMessageSize,
+		fragmentSize:   options.FragmentSize,
+		closeTimeout:   options.CloseTimeout,
+	}
+	if conn.maxMessageSize <= 0 {
+		conn.maxMessageSize = defaultMessageSize
+	}
+	if conn.closeTimeout <= 0 {
+		conn.closeTimeout = defaultCloseTimeout
+	}
+	return conn
+}
+
+// Subprotocol returns the negotiated subprotocol, empty if none was selected
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+func (c *Conn) SetPingHandler(handler func(data []byte) error) {
+	c.pingHandler = handler
+}
+
+func (c *Conn) SetPongHandler(handler func(data []byte) error) {
+	c.pongHandler = handler
+}
+
+func (c *Conn) writeFrame(fin bool, opcode MessageType, payload []byte) error {
+	header := make([]byte, 2, 10)
+	if fin {
+		header[0] = 0x80
+	}
+	header[0] |= byte(opcode)
+
+	// Server frames are never masked
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteMessage sends a text or binary message, it is split into frames if a fragment size is set
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	if messageType == TextMessage && !utf8.Valid(data) {
+		return errors.New("websocket: text message is not valid utf-8")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+
+	opcode := messageType
+	for {
+		payload := data
+		fin := true
+		if c.fragmentSize > 0 && len(data) > c.fragmentSize {
+			payload = data[:c.fragmentSize]
+			fin = false
+		}
+
+		if err := c.writeFrame(fin, opcode, payload); err != nil {
+			return err
+		}
+
+		data = data[len(payload):]
+		opcode = continuationMessage
+		if fin {
+			break
+		}
+	}
+	return c.rw.Flush()
+}
+
+func (c *Conn) WriteText(text string) error {
+	return c.WriteMessage(TextMessage, []byte(text))
+}
+
+func (c *Conn) WriteBinary(data []byte) error {
+	return c.WriteMessage(BinaryMessage, data)
+}
+
+func (c *Conn) writeControl(opcode MessageType, payload []byte) error {
+	if len(payload) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrClosed
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	if err := c.writeFrame(true, opcode, payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+func (c *Conn) Ping(data []byte) error {
+	return c.writeControl(PingMessage, data)
+}
+
+func (c *Conn) Pong(data []byte) error {
+	return c.writeControl(PongMessage, data)
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	payload := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(reason)), uint16(code))
+	return append(payload, reason...)
+}
+
+// WriteClose sends a close frame, no messages can be written after it
+func (c *Conn) WriteClose(code int, reason string) error {
+	return c.writeControl(CloseMessage, closePayload(code, reason))
+}
+
+// Sends a close frame for a protocol violation and drops the connection
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	c.conn.Close()
+	return &CloseError{Code: code, Reason: reason}
+}
+
+func (c *Conn) readFrame() (*frame, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+		return nil, err
+	}
+
+	fr := &frame{
+		fin:    header[0]&0x80 != 0,
+		opcode: MessageType(header[0] & 0x0F),
+	}
+
+	// No extensions are negotiated so reserved bits must be zero
+	if header[0]&0x70 != 0 {
+		return nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	switch fr.opcode {
+	case continuationMessage, TextMessage, BinaryMessage, CloseMessage, PingMessage, PongMessage:
+	default:
+		return nil, c.fail(CloseProtocolError, "unknown opcode")
+	}
+
+	// Clients must mask every frame
+	if header[1]&0x80 == 0 {
+		return nil, c.fail(CloseProtocolError, "client frame is not masked")
+	}
+
+	length := int64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.rw, extended[:]); err != nil {
+			return nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.rw, extended[:]); err != nil {
+			return nil, err
+		}
+		if extended[0]&0x80 != 0 {
+			return nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(binary.BigEndian.Uint64(extended[:]))
+	}
+
+	if fr.opcode.isControl() && (length > maxControlPayload || !fr.fin) {
+		return nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length > c.maxMessageSize {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return nil, err
+	}
+
+	fr.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.rw, fr.payload); err != nil {
+		return nil, err
+	}
+	for i := range fr.payload {
+		fr.payload[i] ^= mask[i%4]
+	}
+
+	return fr, nil
+}
+
+// Codes reserved for local use or not defined by the protocol can not be sent
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= 1000 && code <= 1011:
+		return code != 1004 && code != 1005 && code != 1006
+	default:
+		return false
+	}
+}
+
+// Answers the close frame of the client, returns the close error to stop reading
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Reason) {
+			return c.fail(CloseInvalidPayloadData, "invalid close reason")
+		}
+	}
+
+	c.writeControl(CloseMessage, closePayload(closeErr.Code, ""))
+	c.conn.Close()
+	return closeErr
+}
+
+func (c *Conn) handleControl(fr *frame) error {
+	switch fr.opcode {
+	case PingMessage:
+		if c.pingHandler != nil {
+			return c.pingHandler(fr.payload)
+		}
+		if err := c.Pong(fr.payload); err != nil && err != ErrClosed {
+			return err
+		}
+	case PongMessage:
+		if c.pongHandler != nil {
+			return c.pongHandler(fr.payload)
+		}
+	case CloseMessage:
+		return c.handleClose(fr.payload)
+	}
+	return nil
+}
+
+// ReadMessage returns the next text or binary message, fragmented messages are joined
+// Pings are answered, a close frame from the client is answered and returned as a *CloseError
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var messageType MessageType
+	var message []byte
+
+	for {
+		fr, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if fr.opcode.isControl() {
+			if err := c.handleControl(fr); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		// A message starts with a text or binary frame and continues with continuation frames
+		if fr.opcode == continuationMessage {
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		} else {
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = fr.opcode
+			message = make([]byte, 0, len(fr.payload))
+		}
+
+		if int64(len(message)+len(fr.payload)) > c.maxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		message = append(message, fr.payload...)
+
+		if fr.fin {
+			break
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(message) {
+		return 0, nil, c.fail(CloseInvalidPayloadData, "text message is not valid utf-8")
+	}
+	return messageType, message, nil
+}
+
+// Close sends a close frame, waits for the client to answer it and closes the connection
+// It should not be called while another goroutine is reading
+func (c *Conn) Close(code int, reason string) error {
+	if err := c.WriteClose(code, reason); err != nil && err != ErrClosed {
+		c.conn.Close()
+		return err
+	}
+
+	// Drop messages until the close frame of the client arrives
+	c.conn.SetReadDeadline(time.Now().Add(c.closeTimeout))
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			break
+		}
+	}
+	return c.conn.Close()
+}
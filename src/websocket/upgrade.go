@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cccaaannn/gohst/src/constant"
+	"github.com/cccaaannn/gohst/src/request"
+	"github.com/cccaaannn/gohst/src/response"
+)
+
+const (
+	acceptGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	supportedVersion    = "13"
+	keyHeader           = "Sec-WebSocket-Key"
+	versionHeader       = "Sec-WebSocket-Version"
+	acceptHeader        = "Sec-WebSocket-Accept"
+	protocolHeader      = "Sec-WebSocket-Protocol"
+	defaultMessageSize  = 1 << 20
+	defaultCloseTimeout = 5 * time.Second
+)
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+type Options struct {
+	// Subprotocols supported by the server in order of preference
+	Subprotocols []string
+	// Messages larger than this are rejected with a close frame, defaults to 1MB
+	MaxMessageSize int64
+	// Messages larger than this are sent in multiple frames, zero sends every message in a single frame
+	FragmentSize int
+	// Decides whether the request origin is allowed, all origins are allowed if it is nil
+	CheckOrigin func(req *request.Request) bool
+	// How long Close waits for the client to answer the close frame, defaults to 5 seconds
+	CloseTimeout time.Duration
+}
+
+func computeAcceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// First server subprotocol the client also offered, empty if there is no match
+func selectSubprotocol(req *request.Request, supported []string) string {
+	offered := req.Headers.Join(protocolHeader)
+	for _, protocol := range supported {
+		for _, part := range strings.Split(offered, ",") {
+			if strings.TrimSpace(part) == protocol {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+func reject(res *response.Response, status constant.HTTPStatusCode, reason string) error {
+	res.StatusCode = status
+	res.Body = reason
+	return fmt.Errorf("%w: %s", ErrBadHandshake, reason)
+}
+
+// Upgrade validates the websocket handshake and switches the connection to the websocket protocol
+// On failure the response is set to an error status and the handler should return
+func Upgrade(req *request.Request, res *response.Response, options *Options) (*Conn, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	if req.Method != constant.GetMethod {
+		return nil, reject(res, constant.MethodNotAllowedStatus, "websocket handshake must use GET")
+	}
+	if !req.Headers.HasToken(constant.ConnectionHeader.String(), "upgrade") {
+		return nil, reject(res, constant.BadRequestStatus, "missing connection upgrade header")
+	}
+	if !req.Headers.HasToken(constant.UpgradeHeader.String(), "websocket") {
+		return nil, reject(res, constant.BadRequestStatus, "missing websocket upgrade header")
+	}
+
+	if req.Headers.Get(versionHeader) != supportedVersion {
+		res.Headers.Set(versionHeader, supportedVersion)
+		return nil, reject(res, constant.UpgradeRequiredStatus, "unsupported websocket version")
+	}
+
+	key := strings.TrimSpace(req.Headers.Get(keyHeader))
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, reject(res, constant.BadRequestStatus, "invalid websocket key")
+	}
+
+	if options.CheckOrigin != nil && !options.CheckOrigin(req) {
+		return nil, reject(res, constant.ForbiddenStatus, "origin not allowed")
+	}
+
+	netConn, rw, err := res.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	subprotocol := selectSubprotocol(req, options.Subprotocols)
+
+	handshake := fmt.Sprintf(
+		"%s %s %s\r\n%s: websocket\r\n%s: Upgrade\r\n%s: %s\r\n",
+		constant.HTTPVersion, constant.SwitchingProtocolsStatus.String(), constant.SwitchingProtocolsStatus.Verb(),
+		constant.UpgradeHeader.String(),
+		constant.ConnectionHeader.String(),
+		acceptHeader, computeAcceptKey(key),
+	)
+	if subprotocol != "" {
+		handshake += fmt.Sprintf("%s: %s\r\n", protocolHeader, subprotocol)
+	}
+	handshake += "\r\n"
+
+	if _, err := rw.WriteString(handshake); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return createConn(netConn, rw, subprotocol, options), nil
+}